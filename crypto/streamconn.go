@@ -0,0 +1,184 @@
+package crypto
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// maxChunkPayload 是单个分块明文载荷的最大长度，和 shadowsocks 的
+// AEAD 分块协议保持一致：2 字节长度字段的高 2 位恒为 0，故上限为
+// 0x3FFF。
+const maxChunkPayload = 0x3FFF
+
+// StreamConn 包装一个 net.Conn，在其上透明地做 AEAD 分块加解密：
+// 每个分块是 `[2字节长度|长度的认证标签|载荷|载荷的认证标签]`，
+// nonce 由连接建立时随机生成的 salt 经 HKDF-SHA1 派生出子密钥后，
+// 再按分块顺序递增得到。
+type StreamConn struct {
+	net.Conn
+
+	method    Method
+	masterKey []byte
+	keySize   int
+
+	wMu    sync.Mutex
+	wAEAD  cipher.AEAD
+	wNonce []byte
+
+	rMu    sync.Mutex
+	rAEAD  cipher.AEAD
+	rNonce []byte
+	rBuf   []byte // 上一个分块里尚未被 Read 取走的明文
+}
+
+// NewStreamConn 用给定的加密方式和主密钥包装 conn。
+func NewStreamConn(conn net.Conn, method Method, masterKey []byte) (*StreamConn, error) {
+	size, err := KeySize(method)
+	if err != nil {
+		return nil, err
+	}
+	if len(masterKey) != size {
+		return nil, fmt.Errorf("主密钥长度应为 %d 字节，实际为 %d 字节", size, len(masterKey))
+	}
+	return &StreamConn{Conn: conn, method: method, masterKey: masterKey, keySize: size}, nil
+}
+
+// ensureWriter 在首次写入时生成随机 salt，以明文发送给对端，并据此
+// 派生出本方向使用的 AEAD。
+func (c *StreamConn) ensureWriter() error {
+	if c.wAEAD != nil {
+		return nil
+	}
+
+	salt := make([]byte, c.keySize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return fmt.Errorf("生成 salt 失败: %w", err)
+	}
+	aead, err := newAEAD(c.method, c.masterKey, salt)
+	if err != nil {
+		return err
+	}
+	if _, err := c.Conn.Write(salt); err != nil {
+		return fmt.Errorf("发送 salt 失败: %w", err)
+	}
+
+	c.wAEAD = aead
+	c.wNonce = make([]byte, aead.NonceSize())
+	return nil
+}
+
+// ensureReader 在首次读取时读取对端发来的 salt，并据此派生出本方向
+// 使用的 AEAD。
+func (c *StreamConn) ensureReader() error {
+	if c.rAEAD != nil {
+		return nil
+	}
+
+	salt := make([]byte, c.keySize)
+	if _, err := io.ReadFull(c.Conn, salt); err != nil {
+		return fmt.Errorf("读取 salt 失败: %w", err)
+	}
+	aead, err := newAEAD(c.method, c.masterKey, salt)
+	if err != nil {
+		return err
+	}
+
+	c.rAEAD = aead
+	c.rNonce = make([]byte, aead.NonceSize())
+	return nil
+}
+
+// incNonce 以小端方式给 nonce 加一，和 shadowsocks 的实现一致。
+func incNonce(nonce []byte) {
+	for i := range nonce {
+		nonce[i]++
+		if nonce[i] != 0 {
+			return
+		}
+	}
+}
+
+// Write 把 b 按 maxChunkPayload 切块，加密后写入底层连接。
+func (c *StreamConn) Write(b []byte) (int, error) {
+	c.wMu.Lock()
+	defer c.wMu.Unlock()
+
+	if err := c.ensureWriter(); err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for len(b) > 0 {
+		n := len(b)
+		if n > maxChunkPayload {
+			n = maxChunkPayload
+		}
+		chunk := b[:n]
+		b = b[n:]
+
+		lenBuf := make([]byte, 2)
+		binary.BigEndian.PutUint16(lenBuf, uint16(n))
+
+		sealedLen := c.wAEAD.Seal(nil, c.wNonce, lenBuf, nil)
+		incNonce(c.wNonce)
+		sealedPayload := c.wAEAD.Seal(nil, c.wNonce, chunk, nil)
+		incNonce(c.wNonce)
+
+		if _, err := c.Conn.Write(append(sealedLen, sealedPayload...)); err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// Read 从底层连接读取并解密下一个分块，必要时把多出来的明文缓存到
+// 下一次 Read 调用。
+func (c *StreamConn) Read(b []byte) (int, error) {
+	c.rMu.Lock()
+	defer c.rMu.Unlock()
+
+	if len(c.rBuf) > 0 {
+		n := copy(b, c.rBuf)
+		c.rBuf = c.rBuf[n:]
+		return n, nil
+	}
+
+	if err := c.ensureReader(); err != nil {
+		return 0, err
+	}
+
+	tagSize := c.rAEAD.Overhead()
+
+	sealedLen := make([]byte, 2+tagSize)
+	if _, err := io.ReadFull(c.Conn, sealedLen); err != nil {
+		return 0, err
+	}
+	lenBuf, err := c.rAEAD.Open(nil, c.rNonce, sealedLen, nil)
+	if err != nil {
+		return 0, fmt.Errorf("解密分块长度失败: %w", err)
+	}
+	incNonce(c.rNonce)
+
+	payloadLen := binary.BigEndian.Uint16(lenBuf)
+	sealedPayload := make([]byte, int(payloadLen)+tagSize)
+	if _, err := io.ReadFull(c.Conn, sealedPayload); err != nil {
+		return 0, err
+	}
+	payload, err := c.rAEAD.Open(nil, c.rNonce, sealedPayload, nil)
+	if err != nil {
+		return 0, fmt.Errorf("解密分块载荷失败: %w", err)
+	}
+	incNonce(c.rNonce)
+
+	n := copy(b, payload)
+	if n < len(payload) {
+		c.rBuf = payload[n:]
+	}
+	return n, nil
+}
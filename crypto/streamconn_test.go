@@ -0,0 +1,103 @@
+package crypto
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStreamConnRoundTrip(t *testing.T) {
+	for _, method := range []Method{AES128GCM, AES256GCM, Chacha20IETFPoly1305} {
+		t.Run(string(method), func(t *testing.T) {
+			key, err := DeriveKey("correct horse battery staple", method)
+			if err != nil {
+				t.Fatalf("DeriveKey 失败: %v", err)
+			}
+
+			serverRaw, clientRaw := net.Pipe()
+			defer serverRaw.Close()
+			defer clientRaw.Close()
+
+			server, err := NewStreamConn(serverRaw, method, key)
+			if err != nil {
+				t.Fatalf("NewStreamConn(server) 失败: %v", err)
+			}
+			client, err := NewStreamConn(clientRaw, method, key)
+			if err != nil {
+				t.Fatalf("NewStreamConn(client) 失败: %v", err)
+			}
+
+			// 写入一段超过 maxChunkPayload 的数据，确保分块逻辑被实际触发。
+			want := bytes.Repeat([]byte("0123456789abcdef"), maxChunkPayload/8)
+
+			errCh := make(chan error, 1)
+			go func() {
+				_, err := client.Write(want)
+				errCh <- err
+			}()
+
+			got := make([]byte, 0, len(want))
+			buf := make([]byte, 4096)
+			for len(got) < len(want) {
+				n, err := server.Read(buf)
+				if err != nil {
+					t.Fatalf("Read 失败: %v", err)
+				}
+				got = append(got, buf[:n]...)
+			}
+
+			if err := <-errCh; err != nil {
+				t.Fatalf("Write 失败: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("往返后数据不一致: got %d 字节, want %d 字节", len(got), len(want))
+			}
+		})
+	}
+}
+
+// memConn 是一个只支持单向字节缓冲的极简 net.Conn 实现，用于在测试里
+// 拿到加密后的原始字节并在送入解密端之前篡改它们。
+type memConn struct {
+	buf *bytes.Buffer
+}
+
+func (c *memConn) Read(p []byte) (int, error)       { return c.buf.Read(p) }
+func (c *memConn) Write(p []byte) (int, error)      { return c.buf.Write(p) }
+func (c *memConn) Close() error                     { return nil }
+func (c *memConn) LocalAddr() net.Addr              { return nil }
+func (c *memConn) RemoteAddr() net.Addr             { return nil }
+func (c *memConn) SetDeadline(time.Time) error      { return nil }
+func (c *memConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *memConn) SetWriteDeadline(time.Time) error { return nil }
+
+func TestStreamConnRejectsTamperedCiphertext(t *testing.T) {
+	method := AES256GCM
+	key, err := DeriveKey("shared secret", method)
+	if err != nil {
+		t.Fatalf("DeriveKey 失败: %v", err)
+	}
+
+	wireBuf := &bytes.Buffer{}
+	client, err := NewStreamConn(&memConn{buf: wireBuf}, method, key)
+	if err != nil {
+		t.Fatalf("NewStreamConn(client) 失败: %v", err)
+	}
+	if _, err := client.Write([]byte("hello")); err != nil {
+		t.Fatalf("Write 失败: %v", err)
+	}
+
+	tampered := append([]byte(nil), wireBuf.Bytes()...)
+	tampered[len(tampered)-1] ^= 0xFF // 翻转载荷密文的最后一个字节（认证标签范围内）
+
+	server, err := NewStreamConn(&memConn{buf: bytes.NewBuffer(tampered)}, method, key)
+	if err != nil {
+		t.Fatalf("NewStreamConn(server) 失败: %v", err)
+	}
+
+	readBuf := make([]byte, 16)
+	if _, err := server.Read(readBuf); err == nil {
+		t.Fatal("期望被篡改的密文解密失败，但 Read 没有返回错误")
+	}
+}
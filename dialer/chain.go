@@ -0,0 +1,74 @@
+// Package dialer 提供可组合的上游拨号器：多个代理跳（SOCKS5、HTTP
+// CONNECT、直连）串联成一条 Chain，再由 Router 根据规则为每个连接
+// 挑选应当使用的 Chain。
+package dialer
+
+import (
+	"fmt"
+
+	"golang.org/x/net/proxy"
+)
+
+// HopType 标识链路中一跳的类型。
+type HopType string
+
+const (
+	HopDirect      HopType = "direct"
+	HopSOCKS5      HopType = "socks5"
+	HopHTTPConnect HopType = "http_connect"
+)
+
+// HopConfig 描述链路中的一跳，对应 JSON 配置里 chain 的单个元素。
+type HopConfig struct {
+	Type     HopType `json:"type"`
+	Addr     string  `json:"addr,omitempty"`
+	Username string  `json:"username,omitempty"`
+	Password string  `json:"password,omitempty"`
+}
+
+// ChainConfig 是一条命名链路的 JSON 配置：按顺序列出的若干跳，
+// 最终请求会先拨通第一跳，再经由它拨通第二跳，以此类推。
+type ChainConfig struct {
+	Hops []HopConfig `json:"hops"`
+}
+
+// Chain 是一条已经构建好的、可直接使用的拨号链路。
+type Chain struct {
+	Name   string
+	Dialer proxy.Dialer
+}
+
+// buildChain 将 ChainConfig 编译为可用的 proxy.Dialer：从最靠近本机的
+// 一跳开始，逐跳把前一跳得到的 Dialer 作为下一跳的转发拨号器。
+func buildChain(name string, cfg ChainConfig) (*Chain, error) {
+	var d proxy.Dialer = proxy.Direct
+
+	for i, hop := range cfg.Hops {
+		switch hop.Type {
+		case HopDirect, "":
+			d = proxy.Direct
+		case HopSOCKS5:
+			if hop.Addr == "" {
+				return nil, fmt.Errorf("chain %q 第 %d 跳: socks5 缺少 addr", name, i)
+			}
+			var auth *proxy.Auth
+			if hop.Username != "" {
+				auth = &proxy.Auth{User: hop.Username, Password: hop.Password}
+			}
+			nd, err := proxy.SOCKS5("tcp", hop.Addr, auth, d)
+			if err != nil {
+				return nil, fmt.Errorf("chain %q 第 %d 跳: 创建 socks5 拨号器失败: %w", name, i, err)
+			}
+			d = nd
+		case HopHTTPConnect:
+			if hop.Addr == "" {
+				return nil, fmt.Errorf("chain %q 第 %d 跳: http_connect 缺少 addr", name, i)
+			}
+			d = newHTTPConnectDialer(hop.Addr, hop.Username, hop.Password, d)
+		default:
+			return nil, fmt.Errorf("chain %q 第 %d 跳: 未知的跳类型 %q", name, i, hop.Type)
+		}
+	}
+
+	return &Chain{Name: name, Dialer: d}, nil
+}
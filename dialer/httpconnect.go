@@ -0,0 +1,79 @@
+package dialer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/proxy"
+)
+
+// httpConnectDialer 通过向上游 HTTP 代理发送 CONNECT 请求来建立隧道，
+// 实现了 proxy.Dialer，可以和 SOCKS5 拨号器一样被串进 Chain 里。
+type httpConnectDialer struct {
+	proxyAddr string
+	username  string
+	password  string
+	forward   proxy.Dialer
+}
+
+func newHTTPConnectDialer(proxyAddr, username, password string, forward proxy.Dialer) proxy.Dialer {
+	return &httpConnectDialer{proxyAddr: proxyAddr, username: username, password: password, forward: forward}
+}
+
+// Dial 先经由 forward 拨通 HTTP 代理本身，再对其发起 CONNECT 请求，
+// 只有收到 2xx 响应才把底层连接交回给调用方使用。
+func (d *httpConnectDialer) Dial(network, addr string) (net.Conn, error) {
+	conn, err := d.forward.Dial("tcp", d.proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("http_connect: 拨号代理 %s 失败: %w", d.proxyAddr, err)
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if d.username != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(d.username + ":" + d.password))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("http_connect: 发送 CONNECT 请求失败: %w", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("http_connect: 读取 CONNECT 响应失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		conn.Close()
+		return nil, fmt.Errorf("http_connect: 代理拒绝 CONNECT，状态码 %d", resp.StatusCode)
+	}
+
+	// 代理可能把隧道数据和 CONNECT 响应一起发了过来，已经被 br 缓冲的
+	// 那部分字节不会再出现在 conn 的底层 socket 里，必须继续通过 br
+	// 读取，否则流的开头会被吞掉。
+	return &bufferedConn{Conn: conn, r: br}, nil
+}
+
+// bufferedConn 让后续的 Read 调用继续经过已经消费了 HTTP 响应头的
+// bufio.Reader，而不是绕开它直接读底层连接。
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
@@ -0,0 +1,183 @@
+package dialer
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/proxy"
+)
+
+// MatchConfig 描述一条路由规则的匹配条件。各字段之间是“与”的关系；
+// Host/CIDR/DomainSuffix/SrcCIDR 这几个字段内若填写多个以逗号分隔的
+// 值，视作该字段的“或”（例如 "1.1.1.1,8.8.8.8"）。Regex 本身已经可以
+// 用 "|" 表达“或”，因此不做逗号拆分。留空的字段不参与匹配。
+type MatchConfig struct {
+	Host         string `json:"host,omitempty"`          // 目的主机精确匹配，逗号分隔多个取“或”
+	CIDR         string `json:"cidr,omitempty"`          // 目的地址 CIDR 匹配，逗号分隔多个取“或”
+	DomainSuffix string `json:"domain_suffix,omitempty"` // 目的主机后缀匹配，逗号分隔多个取“或”
+	Regex        string `json:"regex,omitempty"`         // 目的主机正则匹配
+	SrcCIDR      string `json:"src_cidr,omitempty"`      // 客户端来源地址 CIDR 匹配，逗号分隔多个取“或”
+}
+
+// RouteConfig 是配置文件里 "routes" 数组的单个元素：
+// {"match": {...}, "via": "chain-name"}。
+type RouteConfig struct {
+	Match MatchConfig `json:"match"`
+	Via   string      `json:"via"`
+}
+
+// compiledRule 是 RouteConfig 解析后的可执行形式。每个切片字段是同一
+// MatchConfig 字段按逗号拆分后的结果，命中其中任意一个即算该字段匹配。
+type compiledRule struct {
+	hosts          []string
+	cidrs          []*net.IPNet
+	domainSuffixes []string
+	regex          *regexp.Regexp
+	srcCIDRs       []*net.IPNet
+	chain          proxy.Dialer
+	chainName      string
+}
+
+// splitCommaList 把逗号分隔的字段值拆成去除首尾空白后的非空片段；
+// 空字符串返回 nil，表示该字段不参与匹配。
+func splitCommaList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Router 根据目的地址和客户端来源地址，从一组已编译的规则中为每个
+// 连接挑选应当使用的拨号链路；没有规则命中时回退到 proxy.Direct。
+// Router 本身构建后不可变：热加载时由调用方（supervisor 包）整体构建
+// 一个新的 Router 并原子地替换指针，而不是就地修改这一个实例。
+type Router struct {
+	rules []compiledRule
+}
+
+// NewRouter 根据命名的链路配置和按顺序求值的规则构建 Router。
+func NewRouter(chains map[string]ChainConfig, routes []RouteConfig) (*Router, error) {
+	built := make(map[string]*Chain, len(chains))
+	for name, cfg := range chains {
+		c, err := buildChain(name, cfg)
+		if err != nil {
+			return nil, err
+		}
+		built[name] = c
+	}
+
+	rules := make([]compiledRule, 0, len(routes))
+	for i, rt := range routes {
+		chain, ok := built[rt.Via]
+		if !ok {
+			return nil, fmt.Errorf("路由规则 #%d 引用了未定义的链路 %q", i, rt.Via)
+		}
+
+		rule := compiledRule{
+			hosts:          splitCommaList(rt.Match.Host),
+			domainSuffixes: splitCommaList(rt.Match.DomainSuffix),
+			chain:          chain.Dialer,
+			chainName:      rt.Via,
+		}
+
+		for _, s := range splitCommaList(rt.Match.CIDR) {
+			_, ipnet, err := net.ParseCIDR(s)
+			if err != nil {
+				return nil, fmt.Errorf("路由规则 #%d: 非法的 cidr %q: %w", i, s, err)
+			}
+			rule.cidrs = append(rule.cidrs, ipnet)
+		}
+		for _, s := range splitCommaList(rt.Match.SrcCIDR) {
+			_, ipnet, err := net.ParseCIDR(s)
+			if err != nil {
+				return nil, fmt.Errorf("路由规则 #%d: 非法的 src_cidr %q: %w", i, s, err)
+			}
+			rule.srcCIDRs = append(rule.srcCIDRs, ipnet)
+		}
+		if rt.Match.Regex != "" {
+			re, err := regexp.Compile(rt.Match.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("路由规则 #%d: 非法的 regex %q: %w", i, rt.Match.Regex, err)
+			}
+			rule.regex = re
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return &Router{rules: rules}, nil
+}
+
+// DialerFor 为目的地址 destHostPort（"host:port"）和客户端来源地址
+// srcAddr（"ip:port"）挑选拨号器。规则按配置顺序求值，首个命中的
+// 规则生效；全部不命中时回退到 proxy.Direct。
+func (r *Router) DialerFor(destHostPort, srcAddr string) proxy.Dialer {
+	host, _, err := net.SplitHostPort(destHostPort)
+	if err != nil {
+		host = destHostPort
+	}
+	destIP := net.ParseIP(host)
+
+	var srcIP net.IP
+	if srcHost, _, err := net.SplitHostPort(srcAddr); err == nil {
+		srcIP = net.ParseIP(srcHost)
+	}
+
+	for _, rule := range r.rules {
+		if len(rule.hosts) > 0 && !containsString(rule.hosts, host) {
+			continue
+		}
+		if len(rule.domainSuffixes) > 0 && !anyHasSuffix(rule.domainSuffixes, host) {
+			continue
+		}
+		if rule.regex != nil && !rule.regex.MatchString(host) {
+			continue
+		}
+		if len(rule.cidrs) > 0 && (destIP == nil || !anyContainsIP(rule.cidrs, destIP)) {
+			continue
+		}
+		if len(rule.srcCIDRs) > 0 && (srcIP == nil || !anyContainsIP(rule.srcCIDRs, srcIP)) {
+			continue
+		}
+		return rule.chain
+	}
+
+	return proxy.Direct
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func anyHasSuffix(suffixes []string, s string) bool {
+	for _, suf := range suffixes {
+		if strings.HasSuffix(s, suf) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyContainsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
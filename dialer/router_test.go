@@ -0,0 +1,111 @@
+package dialer
+
+import (
+	"testing"
+
+	"golang.org/x/net/proxy"
+)
+
+func mustRouter(t *testing.T, routes []RouteConfig) *Router {
+	t.Helper()
+	chains := map[string]ChainConfig{
+		"a": {Hops: []HopConfig{{Type: HopSOCKS5, Addr: "127.0.0.1:11080"}}},
+		"b": {Hops: []HopConfig{{Type: HopSOCKS5, Addr: "127.0.0.1:11081"}}},
+	}
+	r, err := NewRouter(chains, routes)
+	if err != nil {
+		t.Fatalf("NewRouter 失败: %v", err)
+	}
+	return r
+}
+
+func TestDialerForCommaSeparatedHostIsOR(t *testing.T) {
+	r := mustRouter(t, []RouteConfig{
+		{Match: MatchConfig{Host: "a.example.com, b.example.com"}, Via: "a"},
+	})
+
+	for _, host := range []string{"a.example.com", "b.example.com"} {
+		if d := r.DialerFor(host+":443", "1.2.3.4:1111"); d == proxy.Direct {
+			t.Errorf("host %q 应命中规则并返回 chain a，却回退到了 Direct", host)
+		}
+	}
+
+	if d := r.DialerFor("c.example.com:443", "1.2.3.4:1111"); d != proxy.Direct {
+		t.Error("未列出的 host 应回退到 Direct")
+	}
+}
+
+func TestDialerForCommaSeparatedDomainSuffixIsOR(t *testing.T) {
+	r := mustRouter(t, []RouteConfig{
+		{Match: MatchConfig{DomainSuffix: ".cn,.jp"}, Via: "a"},
+	})
+
+	for _, host := range []string{"www.example.cn", "www.example.jp"} {
+		if d := r.DialerFor(host+":443", "1.2.3.4:1111"); d == proxy.Direct {
+			t.Errorf("host %q 应命中后缀规则，却回退到了 Direct", host)
+		}
+	}
+	if d := r.DialerFor("www.example.com:443", "1.2.3.4:1111"); d != proxy.Direct {
+		t.Error("不匹配任何后缀时应回退到 Direct")
+	}
+}
+
+func TestDialerForCommaSeparatedCIDRIsOR(t *testing.T) {
+	r := mustRouter(t, []RouteConfig{
+		{Match: MatchConfig{CIDR: "10.0.0.0/8,192.168.0.0/16"}, Via: "a"},
+	})
+
+	for _, ip := range []string{"10.1.2.3", "192.168.1.1"} {
+		if d := r.DialerFor(ip+":443", "1.2.3.4:1111"); d == proxy.Direct {
+			t.Errorf("ip %q 应命中 CIDR 规则，却回退到了 Direct", ip)
+		}
+	}
+	if d := r.DialerFor("8.8.8.8:443", "1.2.3.4:1111"); d != proxy.Direct {
+		t.Error("不在任何 CIDR 内时应回退到 Direct")
+	}
+}
+
+func TestDialerForCommaSeparatedSrcCIDRIsOR(t *testing.T) {
+	r := mustRouter(t, []RouteConfig{
+		{Match: MatchConfig{SrcCIDR: "10.0.0.0/8,172.16.0.0/12"}, Via: "a"},
+	})
+
+	for _, src := range []string{"10.1.1.1:5555", "172.16.5.5:5555"} {
+		if d := r.DialerFor("example.com:443", src); d == proxy.Direct {
+			t.Errorf("src %q 应命中 src_cidr 规则，却回退到了 Direct", src)
+		}
+	}
+	if d := r.DialerFor("example.com:443", "8.8.8.8:5555"); d != proxy.Direct {
+		t.Error("来源地址不在任何 src_cidr 内时应回退到 Direct")
+	}
+}
+
+func TestDialerForFirstMatchingRuleWins(t *testing.T) {
+	r := mustRouter(t, []RouteConfig{
+		{Match: MatchConfig{Host: "example.com"}, Via: "a"},
+		{Match: MatchConfig{Host: "example.com"}, Via: "b"},
+	})
+
+	if d := r.DialerFor("example.com:443", "1.2.3.4:1111"); d != r.rules[0].chain {
+		t.Error("应使用第一条命中的规则 (chain a)，而不是后面的规则")
+	}
+}
+
+func TestNewRouterRejectsInvalidCIDRInCommaList(t *testing.T) {
+	chains := map[string]ChainConfig{"a": {}}
+	_, err := NewRouter(chains, []RouteConfig{
+		{Match: MatchConfig{CIDR: "10.0.0.0/8,not-a-cidr"}, Via: "a"},
+	})
+	if err == nil {
+		t.Fatal("期望逗号列表中的非法 CIDR 导致 NewRouter 返回错误")
+	}
+}
+
+func TestNewRouterRejectsUnknownChain(t *testing.T) {
+	_, err := NewRouter(map[string]ChainConfig{}, []RouteConfig{
+		{Match: MatchConfig{Host: "example.com"}, Via: "missing"},
+	})
+	if err == nil {
+		t.Fatal("期望引用未定义链路时 NewRouter 返回错误")
+	}
+}
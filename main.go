@@ -4,44 +4,124 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strings"
-	"sync"
 	"syscall"
 
 	"golang.org/x/net/proxy"
+
+	"github.com/lingyicute/Go-TCP-Proxy/crypto"
+	"github.com/lingyicute/Go-TCP-Proxy/manager"
+	"github.com/lingyicute/Go-TCP-Proxy/pipe"
+	"github.com/lingyicute/Go-TCP-Proxy/pkg/tunnel"
+	"github.com/lingyicute/Go-TCP-Proxy/socks5"
+	"github.com/lingyicute/Go-TCP-Proxy/supervisor"
 )
 
 // Config 结构体用于定义配置项，并与JSON文件对应
 type Config struct {
-	LocalAddr string `json:"local_addr"`
-	RemoteAddr string `json:"remote_addr"`
-	SocksAddr string `json:"socks_addr"`
+	// Mode 决定本进程扮演的角色："forward"（默认，经由上游 SOCKS5
+	// 做端口转发）、"socks5"（本进程自身充当 SOCKS5 服务端）或
+	// "tunnel"（与 forward 共用 LocalAddr/RemoteAddr/SocksAddr，但
+	// 经由 pkg/tunnel 的可插拔 Router/Handler 框架转发）。
+	Mode string `json:"mode"`
+
+	// supervisor.Config 匿名内嵌：LocalAddr/RemoteAddr/SocksAddr/
+	// Chains/Routes 这些字段会受 SIGHUP 或管理端点 /reload 的热加载
+	// 影响，具体的监听器/拨号路由切换逻辑交给 supervisor 包。匿名
+	// 内嵌让 JSON 字段仍然铺平在顶层，和历史配置保持兼容。
+	supervisor.Config
+
+	Socks5 socks5.Config `json:"socks5,omitempty"`
+
+	// EncryptRole 决定本地↔远程这一跳是否加密，以及由哪一侧持有 AEAD
+	// 的发送/接收方向："client"（本进程拨出的一端加密）、"server"
+	// （本进程接受连接的一端加密）或 "off"（不加密，默认值）。
+	EncryptRole   string `json:"encrypt_role,omitempty"`
+	EncryptMethod string `json:"encrypt_method,omitempty"` // aes-128-gcm/aes-256-gcm/chacha20-ietf-poly1305
+	EncryptKey    string `json:"encrypt_key,omitempty"`    // 预共享口令，与对端保持一致
+
+	// Manager 启用一个可选的运行时管理/指标端点；ListenAddr 为空则不启用。
+	Manager manager.Config `json:"manager,omitempty"`
 }
 
 // 全局变量，定义程序名称和默认配置
 const appName = "go-proxy-tunnel"
 
+const (
+	modeForward = "forward"
+	modeSocks5  = "socks5"
+	modeTunnel  = "tunnel"
+)
+
 var factoryDefaults = Config{
-	LocalAddr: "127.0.0.1:10808",
-	RemoteAddr: "example.com:80",
-	SocksAddr: "127.0.0.1:1080",
+	Mode: modeForward,
+	Config: supervisor.Config{
+		LocalAddr:  "127.0.0.1:10808",
+		RemoteAddr: "example.com:80",
+		SocksAddr:  "127.0.0.1:1080",
+	},
+	Socks5: socks5.Config{
+		ListenAddr: "127.0.0.1:1081",
+	},
+}
+
+// encryptSetup 描述本地↔远程这一跳启用的加密参数；nil 表示不加密。
+type encryptSetup struct {
+	role   string // "client" 或 "server"
+	method crypto.Method
+	key    []byte
+}
+
+// buildEncryptSetup 根据配置构造 encryptSetup；encrypt_role 为空或
+// "off" 时返回 nil，表示不加密。
+func buildEncryptSetup(cfg *Config) (*encryptSetup, error) {
+	if cfg.EncryptRole == "" || cfg.EncryptRole == "off" {
+		return nil, nil
+	}
+	if cfg.EncryptRole != "client" && cfg.EncryptRole != "server" {
+		return nil, fmt.Errorf("未知的 encrypt_role: %q", cfg.EncryptRole)
+	}
+
+	method := crypto.Method(cfg.EncryptMethod)
+	if method == "" {
+		method = crypto.AES256GCM
+	}
+	key, err := crypto.DeriveKey(cfg.EncryptKey, method)
+	if err != nil {
+		return nil, fmt.Errorf("派生加密密钥失败: %w", err)
+	}
+	return &encryptSetup{role: cfg.EncryptRole, method: method, key: key}, nil
 }
 
 // ---- 网络处理核心----
-func handleConnection(clientConn net.Conn, remoteAddr string, dialer proxy.Dialer, wg *sync.WaitGroup) {
-	// 通知 main 函数，此连接的处理已结束
-	defer wg.Done()
+func handleConnection(clientConn net.Conn, remoteAddr string, d proxy.Dialer, enc *encryptSetup, registry *manager.Registry) {
 	defer clientConn.Close()
 
 	log.Printf("客户端 %s 已连接，准备通过代理连接到 %s", clientConn.RemoteAddr(), remoteAddr)
 
-	remoteConn, err := dialer.Dial("tcp", remoteAddr)
+	if registry != nil {
+		var entry *manager.ConnEntry
+		clientConn, entry = registry.Register(clientConn, remoteAddr)
+		defer registry.Unregister(entry.ID)
+	}
+
+	if enc != nil && enc.role == "server" {
+		// 本进程接受的这一端就是加密隧道的物理连接，需要先解除加密
+		// 外壳，再把明文转发给下游。
+		wrapped, err := crypto.NewStreamConn(clientConn, enc.method, enc.key)
+		if err != nil {
+			log.Printf("错误：为客户端 %s 建立加密连接失败: %v", clientConn.RemoteAddr(), err)
+			return
+		}
+		clientConn = wrapped
+	}
+
+	remoteConn, err := d.Dial("tcp", remoteAddr)
 	if err != nil {
 		log.Printf("错误：通过代理连接到 %s 失败: %v", remoteAddr, err)
 		return
@@ -49,33 +129,18 @@ func handleConnection(clientConn net.Conn, remoteAddr string, dialer proxy.Diale
 	defer remoteConn.Close()
 	log.Printf("已通过代理成功连接到 %s", remoteAddr)
 
-	var copyWg sync.WaitGroup
-	copyWg.Add(2)
-
-	// Goroutine 1: 从客户端复制到远程
-	go func() {
-		defer copyWg.Done()
-		defer remoteConn.Close() // 加速另一个goroutine的退出
-		if _, err := io.Copy(remoteConn, clientConn); err != nil {
-			// 忽略“连接已关闭”的常规错误，只记录意外错误
-			if !strings.Contains(err.Error(), "use of closed network connection") {
-				log.Printf("从客户端 %s 到远程的数据流错误: %v", clientConn.RemoteAddr(), err)
-			}
+	if enc != nil && enc.role == "client" {
+		// 本进程主动拨出的这一端是加密隧道的物理连接，对端会以
+		// encrypt_role=server 的方式解密。
+		wrapped, err := crypto.NewStreamConn(remoteConn, enc.method, enc.key)
+		if err != nil {
+			log.Printf("错误：为远程连接 %s 建立加密连接失败: %v", remoteAddr, err)
+			return
 		}
-	}()
-
-	// Goroutine 2: 从远程复制到客户端
-	go func() {
-		defer copyWg.Done()
-		defer clientConn.Close() // 加速另一个goroutine的退出
-		if _, err := io.Copy(clientConn, remoteConn); err != nil {
-			if !strings.Contains(err.Error(), "use of closed network connection") {
-				log.Printf("从远程到客户端 %s 的数据流错误: %v", clientConn.RemoteAddr(), err)
-			}
-		}
-	}()
+		remoteConn = wrapped
+	}
 
-	copyWg.Wait()
+	pipe.Bidirectional(clientConn, remoteConn)
 	log.Printf("客户端 %s 的连接已关闭", clientConn.RemoteAddr())
 }
 
@@ -157,9 +222,22 @@ func main() {
 	fmt.Println()
 	fmt.Println(" 请根据提示输入配置信息，直接按回车将使用上次保存的值。")
 	fmt.Println()
-	currentConfig.LocalAddr = readInput(" 请输入本地监听地址和端口", currentConfig.LocalAddr)
-	currentConfig.RemoteAddr = readInput(" 请输入远程目标服务地址和端口", currentConfig.RemoteAddr)
-	currentConfig.SocksAddr = readInput(" 请输入 SOCKS5 代理地址和端口", currentConfig.SocksAddr)
+	currentConfig.Mode = readInput(" 请输入运行模式 (forward=端口转发 / socks5=SOCKS5 服务端 / tunnel=可插拔协议转发)", currentConfig.Mode)
+	if currentConfig.Mode != modeSocks5 && currentConfig.Mode != modeTunnel {
+		currentConfig.Mode = modeForward
+	}
+
+	if currentConfig.Mode == modeSocks5 {
+		currentConfig.Socks5.ListenAddr = readInput(" 请输入 SOCKS5 服务监听地址和端口", currentConfig.Socks5.ListenAddr)
+		currentConfig.Socks5.Username = readInput(" 请输入 SOCKS5 认证用户名（留空则不启用认证）", currentConfig.Socks5.Username)
+		if currentConfig.Socks5.Username != "" {
+			currentConfig.Socks5.Password = readInput(" 请输入 SOCKS5 认证密码", currentConfig.Socks5.Password)
+		}
+	} else {
+		currentConfig.LocalAddr = readInput(" 请输入本地监听地址和端口", currentConfig.LocalAddr)
+		currentConfig.RemoteAddr = readInput(" 请输入远程目标服务地址和端口", currentConfig.RemoteAddr)
+		currentConfig.SocksAddr = readInput(" 请输入 SOCKS5 代理地址和端口", currentConfig.SocksAddr)
+	}
 
 	// 3. 保存最终配置
 	fmt.Println()
@@ -169,57 +247,212 @@ func main() {
 	fmt.Println()
 	log.Println("配置确认，准备启动服务...")
 
-	// 4. 创建 SOCKS5 代理拨号器
-	dialer, err := proxy.SOCKS5("tcp", currentConfig.SocksAddr, nil, proxy.Direct)
+	switch currentConfig.Mode {
+	case modeSocks5:
+		runSocks5(&currentConfig)
+	case modeTunnel:
+		runTunnel(&currentConfig)
+	default:
+		runForward(&currentConfig)
+	}
+}
+
+// runForward 运行端口转发模式：本地监听的每个连接都会按规则选路，
+// 经由某条拨号链路转发到固定的 RemoteAddr。监听地址、固定转发目标和
+// 拨号路由的热加载全部交给 supervisor.Supervisor 负责，本函数只负责
+// 把 handleConnection 接入进去并响应关闭信号。
+func runForward(cfg *Config) {
+	enc, err := buildEncryptSetup(cfg)
 	if err != nil {
-		log.Fatalf("错误：无法创建 SOCKS5 代理拨号器: %v", err)
+		log.Fatalf("错误：初始化加密配置失败: %v", err)
 	}
 
-	// 5. 启动监听
-	listener, err := net.Listen("tcp", currentConfig.LocalAddr)
+	var registry *manager.Registry
+	var mgr *manager.Manager
+	if cfg.Manager.ListenAddr != "" {
+		registry = manager.NewRegistry()
+	}
+
+	var sup *supervisor.Supervisor
+	acceptGate := func() bool {
+		return mgr == nil || mgr.Accepting()
+	}
+	sup, err = supervisor.New(&cfg.Config, func(clientConn net.Conn, remoteAddr string, d proxy.Dialer) {
+		handleConnection(clientConn, remoteAddr, d, enc, registry)
+	}, acceptGate)
 	if err != nil {
-		log.Fatalf("错误：无法监听本地端口 %s: %v", currentConfig.LocalAddr, err)
+		log.Fatalf("错误：构建拨号路由失败: %v", err)
 	}
-	// 保留 defer listener.Close() 作为一种保障，以防程序因 panic 等意外情况退出
-	defer listener.Close()
-	log.Printf("服务已在 %s 成功启动，现在可以连接此端口了。", currentConfig.LocalAddr)
 
-	// 使用 WaitGroup 追踪所有活跃的连接
-	var wgConnections sync.WaitGroup
+	if cfg.Manager.ListenAddr != "" {
+		mgr = manager.NewManager(cfg.Manager.ListenAddr, registry, func() error {
+			return reloadSupervisor(cfg, sup)
+		})
+		go func() {
+			if err := mgr.ListenAndServe(); err != nil {
+				log.Printf("管理端点已退出: %v", err)
+			}
+		}()
+		defer mgr.Stop()
+	}
 
-	// 6. 将 listener.Accept() 放入单独的 goroutine，以实现非阻塞监听
+	if err := sup.Start(); err != nil {
+		log.Fatalf("错误：%v", err)
+	}
+	log.Printf("服务已在 %s 成功启动，现在可以连接此端口了。", cfg.LocalAddr)
+
+	// 监听 SIGHUP 以便在不中断现有连接的情况下重新加载配置。
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	reloadDone := make(chan struct{})
 	go func() {
-		for {
-			clientConn, err := listener.Accept()
-			if err != nil {
-				// 当 listener 被关闭时，Accept会返回错误，此时可以安全退出goroutine
-				if !strings.Contains(err.Error(), "use of closed network connection") {
-					log.Printf("Accept 循环遇到未知错误: %v", err)
-				}
-				break // 退出循环，结束此 goroutine
+		defer close(reloadDone)
+		for range hupChan {
+			if err := reloadSupervisor(cfg, sup); err != nil {
+				log.Printf("SIGHUP 重新加载配置失败: %v", err)
+				continue
 			}
-			// 每接受一个新连接，WaitGroup 计数器加 1
-			wgConnections.Add(1)
-			go handleConnection(clientConn, currentConfig.RemoteAddr, dialer, &wgConnections)
+			log.Println("SIGHUP: 配置已重新加载，已有连接不受影响。")
 		}
 	}()
 
-	// 7. 阻塞主 goroutine，直到收到系统信号
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
-
-	// 8. 执行优雅停机流程
-	fmt.Println()
-	log.Println("收到关闭信号，正在停止服务...")
+	waitForShutdownSignal()
+	signal.Stop(hupChan)
+	close(hupChan)
 
-	// 首先，停止接受新连接。这将导致 Accept() 循环出错并退出。
+	// 首先，停止接受新连接。
 	log.Println("正在停止接受新连接...")
-	listener.Close()
+	sup.Stop()
 
 	// 然后，等待所有已建立的连接处理完成
 	log.Println("等待现有连接处理完成...")
-	wgConnections.Wait()
+	sup.Wait()
+	<-reloadDone
 
 	log.Println("所有连接均已关闭，服务成功退出。")
-}
\ No newline at end of file
+}
+
+// runTunnel 运行 tunnel 模式：用 pkg/tunnel 里的 Server 替代 runForward
+// 的原始字节转发，默认不注册任何 handler，因此行为退化为和 forward
+// 模式一样的 passthrough 转发，只是经由 IRouter 这层可插拔框架实现，
+// 便于后续在此基础上注册按消息 ID 处理的 handler。
+func runTunnel(cfg *Config) {
+	var d proxy.Dialer = proxy.Direct
+	if cfg.SocksAddr != "" {
+		var err error
+		d, err = proxy.SOCKS5("tcp", cfg.SocksAddr, nil, proxy.Direct)
+		if err != nil {
+			log.Fatalf("错误：创建 SOCKS5 代理拨号器失败: %v", err)
+		}
+	}
+
+	server := tunnel.NewServer(tunnel.Config{
+		ListenAddr:   cfg.LocalAddr,
+		UpstreamAddr: cfg.RemoteAddr,
+		Dialer:       d,
+	})
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Start()
+	}()
+
+	select {
+	case err := <-errCh:
+		log.Fatalf("错误：tunnel 服务启动失败: %v", err)
+	case <-waitForShutdownSignalCh():
+	}
+
+	log.Println("正在停止 tunnel 服务...")
+	if err := server.Stop(); err != nil {
+		log.Printf("错误：停止 tunnel 服务失败: %v", err)
+	}
+	<-errCh
+	log.Println("tunnel 服务已退出。")
+}
+
+// reloadSupervisor 重新读取磁盘上的配置并交给 sup.Reload 应用；供
+// SIGHUP 和管理端点的 /reload 共用。
+func reloadSupervisor(cfg *Config, sup *supervisor.Supervisor) error {
+	reloaded, err := loadConfigForReload(cfg)
+	if err != nil {
+		return fmt.Errorf("重新加载配置失败: %w", err)
+	}
+	if err := sup.Reload(&reloaded.Config); err != nil {
+		return fmt.Errorf("应用新配置失败: %w", err)
+	}
+	return nil
+}
+
+// loadConfigForReload 重新读取磁盘上的 config.json，供 SIGHUP 热加载使用。
+func loadConfigForReload(old *Config) (*Config, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	cfg := *old
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// runSocks5 运行 SOCKS5 服务端模式：本进程自身作为代理服务器对外提供服务。
+func runSocks5(cfg *Config) {
+	server := socks5.NewServer(cfg.Socks5)
+
+	var mgr *manager.Manager
+	if cfg.Manager.ListenAddr != "" {
+		registry := manager.NewRegistry()
+		server.SetConnHook(func(conn net.Conn, remoteAddr string) (net.Conn, func()) {
+			wrapped, entry := registry.Register(conn, remoteAddr)
+			return wrapped, func() { registry.Unregister(entry.ID) }
+		})
+
+		// SOCKS5 服务端目前没有可以不重启服务就生效的配置，/reload
+		// 因此是个无操作：端点本身仍然可用于查看统计和控制 accept。
+		mgr = manager.NewManager(cfg.Manager.ListenAddr, registry, func() error { return nil })
+		server.SetAcceptGate(mgr.Accepting)
+		go func() {
+			if err := mgr.ListenAndServe(); err != nil {
+				log.Printf("管理端点已退出: %v", err)
+			}
+		}()
+		defer mgr.Stop()
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		log.Fatalf("错误：SOCKS5 服务启动失败: %v", err)
+	case <-waitForShutdownSignalCh():
+	}
+
+	log.Println("正在停止 SOCKS5 服务...")
+	if err := server.Stop(); err != nil {
+		log.Printf("错误：停止 SOCKS5 服务失败: %v", err)
+	}
+	<-errCh
+	log.Println("SOCKS5 服务已退出。")
+}
+
+// waitForShutdownSignal 阻塞直到收到 SIGINT/SIGTERM，并打印统一的提示信息。
+func waitForShutdownSignal() {
+	<-waitForShutdownSignalCh()
+	fmt.Println()
+	log.Println("收到关闭信号，正在停止服务...")
+}
+
+func waitForShutdownSignalCh() <-chan os.Signal {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	return sigChan
+}
@@ -0,0 +1,7 @@
+package manager
+
+// Config 对应 JSON 配置里的 "manager" 字段。ListenAddr 为空表示不启用
+// 管理端点。
+type Config struct {
+	ListenAddr string `json:"listen_addr,omitempty"`
+}
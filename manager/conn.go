@@ -0,0 +1,37 @@
+package manager
+
+import (
+	"net"
+	"sync/atomic"
+)
+
+// countingConn 包装一个 net.Conn，用原子计数器统计经由它读写的字节数，
+// 使 pipe.Bidirectional 里的 io.Copy 调用自然地喂给这些计数器。
+type countingConn struct {
+	net.Conn
+
+	bytesRead    atomic.Uint64
+	bytesWritten atomic.Uint64
+}
+
+func newCountingConn(conn net.Conn) *countingConn {
+	return &countingConn{Conn: conn}
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.bytesRead.Add(uint64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.bytesWritten.Add(uint64(n))
+	return n, err
+}
+
+// BytesRead 返回目前为止读取到的字节数。
+func (c *countingConn) BytesRead() uint64 { return c.bytesRead.Load() }
+
+// BytesWritten 返回目前为止写出的字节数。
+func (c *countingConn) BytesWritten() uint64 { return c.bytesWritten.Load() }
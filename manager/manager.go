@@ -0,0 +1,172 @@
+// Package manager 实现一个可选的运行时管理/指标端点，风格上参照
+// shadowsocks-manager：暴露每连接统计、累计字节计数、活跃连接列表，
+// 以及关闭连接、重载配置、暂停/恢复 accept 循环等控制操作。
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Manager 承载一个 HTTP 管理端点。
+type Manager struct {
+	listenAddr string
+	registry   *Registry
+	reload     func() error
+
+	accepting atomic.Bool
+	server    *http.Server
+}
+
+// NewManager 创建一个管理端点。reload 在收到 POST /reload 时被调用，
+// 用来触发配置/路由的热加载；可以为 nil，此时 /reload 总是返回成功。
+func NewManager(listenAddr string, registry *Registry, reload func() error) *Manager {
+	m := &Manager{listenAddr: listenAddr, registry: registry, reload: reload}
+	m.accepting.Store(true)
+	return m
+}
+
+// Accepting 报告 accept 循环当前是否应当继续接受新连接。
+func (m *Manager) Accepting() bool {
+	return m.accepting.Load()
+}
+
+// ListenAndServe 启动管理 HTTP 服务，阻塞直到出错或 Stop 被调用。
+func (m *Manager) ListenAndServe() error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", m.handleMetrics)
+	mux.HandleFunc("/conns", m.handleConns)
+	mux.HandleFunc("/conns/", m.handleConnClose)
+	mux.HandleFunc("/reload", m.handleReload)
+	mux.HandleFunc("/accept/toggle", m.handleAcceptToggle)
+
+	m.server = &http.Server{Addr: m.listenAddr, Handler: mux}
+	log.Printf("管理端点已在 %s 启动", m.listenAddr)
+	err := m.server.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// Stop 关闭管理 HTTP 服务。
+func (m *Manager) Stop() error {
+	if m.server == nil {
+		return nil
+	}
+	return m.server.Close()
+}
+
+func (m *Manager) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	total, active, bytesUp, bytesDown := m.registry.Totals()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintf(w, "# HELP go_tcp_proxy_connections_total 累计接受的连接数\n")
+	fmt.Fprintf(w, "# TYPE go_tcp_proxy_connections_total counter\n")
+	fmt.Fprintf(w, "go_tcp_proxy_connections_total %d\n", total)
+
+	fmt.Fprintf(w, "# HELP go_tcp_proxy_connections_active 当前活跃连接数\n")
+	fmt.Fprintf(w, "# TYPE go_tcp_proxy_connections_active gauge\n")
+	fmt.Fprintf(w, "go_tcp_proxy_connections_active %d\n", active)
+
+	fmt.Fprintf(w, "# HELP go_tcp_proxy_bytes_up_total 客户端到远程方向累计字节数\n")
+	fmt.Fprintf(w, "# TYPE go_tcp_proxy_bytes_up_total counter\n")
+	fmt.Fprintf(w, "go_tcp_proxy_bytes_up_total %d\n", bytesUp)
+
+	fmt.Fprintf(w, "# HELP go_tcp_proxy_bytes_down_total 远程到客户端方向累计字节数\n")
+	fmt.Fprintf(w, "# TYPE go_tcp_proxy_bytes_down_total counter\n")
+	fmt.Fprintf(w, "go_tcp_proxy_bytes_down_total %d\n", bytesDown)
+}
+
+// connView 是 ConnEntry 对外展示的 JSON 形状。
+type connView struct {
+	ID         uint64 `json:"id"`
+	ClientAddr string `json:"client_addr"`
+	RemoteAddr string `json:"remote_addr"`
+	StartTime  string `json:"start_time"`
+	BytesUp    uint64 `json:"bytes_up"`
+	BytesDown  uint64 `json:"bytes_down"`
+}
+
+func (m *Manager) handleConns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "仅支持 GET", http.StatusMethodNotAllowed)
+		return
+	}
+
+	entries := m.registry.Snapshot()
+	views := make([]connView, 0, len(entries))
+	for _, e := range entries {
+		views = append(views, connView{
+			ID:         e.ID,
+			ClientAddr: e.ClientAddr,
+			RemoteAddr: e.RemoteAddr,
+			StartTime:  e.StartTime.Format("2006-01-02T15:04:05Z07:00"),
+			BytesUp:    e.BytesUp(),
+			BytesDown:  e.BytesDown(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(views)
+}
+
+// handleConnClose 处理 POST /conns/{id}/close。
+func (m *Manager) handleConnClose(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持 POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/conns/")
+	idStr := strings.TrimSuffix(path, "/close")
+	if idStr == path {
+		http.NotFound(w, r)
+		return
+	}
+
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "非法的连接 id", http.StatusBadRequest)
+		return
+	}
+
+	if err := m.registry.Close(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (m *Manager) handleReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持 POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if m.reload != nil {
+		if err := m.reload(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (m *Manager) handleAcceptToggle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "仅支持 POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	accepting := !m.accepting.Load()
+	m.accepting.Store(accepting)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"accepting": accepting})
+}
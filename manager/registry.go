@@ -0,0 +1,119 @@
+package manager
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConnEntry 记录一条活跃连接的元数据，供 /conns 列表和关闭操作使用。
+type ConnEntry struct {
+	ID         uint64
+	ClientAddr string
+	RemoteAddr string
+	StartTime  time.Time
+
+	conn *countingConn
+}
+
+// BytesUp 返回该连接由客户端发往远程方向累计传输的字节数。
+func (e *ConnEntry) BytesUp() uint64 { return e.conn.BytesRead() }
+
+// BytesDown 返回该连接由远程发往客户端方向累计传输的字节数。
+func (e *ConnEntry) BytesDown() uint64 { return e.conn.BytesWritten() }
+
+// Registry 是所有活跃连接的并发安全登记表，同时维护跨连接的累计计数，
+// 即使连接已经关闭、从表中移除，累计计数也不会丢失。
+type Registry struct {
+	nextID atomic.Uint64
+
+	mu    sync.Mutex
+	conns map[uint64]*ConnEntry
+
+	totalAccepted   atomic.Uint64
+	closedBytesUp   atomic.Uint64
+	closedBytesDown atomic.Uint64
+}
+
+// NewRegistry 创建一个空的连接登记表。
+func NewRegistry() *Registry {
+	return &Registry{conns: make(map[uint64]*ConnEntry)}
+}
+
+// Register 在 Accept 时调用：用 countingConn 包装 clientConn 并分配一个
+// 单调递增的连接 id，返回包装后的连接（调用方应改用它继续收发数据）
+// 以及对应的登记项。
+func (r *Registry) Register(clientConn net.Conn, remoteAddr string) (net.Conn, *ConnEntry) {
+	cc := newCountingConn(clientConn)
+	entry := &ConnEntry{
+		ID:         r.nextID.Add(1),
+		ClientAddr: clientConn.RemoteAddr().String(),
+		RemoteAddr: remoteAddr,
+		StartTime:  time.Now(),
+		conn:       cc,
+	}
+
+	r.mu.Lock()
+	r.conns[entry.ID] = entry
+	r.mu.Unlock()
+	r.totalAccepted.Add(1)
+
+	return cc, entry
+}
+
+// Unregister 在连接处理完毕后调用：把该连接的字节数并入累计计数，
+// 并把它从活跃表中移除。
+func (r *Registry) Unregister(id uint64) {
+	r.mu.Lock()
+	entry, ok := r.conns[id]
+	if ok {
+		delete(r.conns, id)
+	}
+	r.mu.Unlock()
+
+	if ok {
+		r.closedBytesUp.Add(entry.conn.BytesRead())
+		r.closedBytesDown.Add(entry.conn.BytesWritten())
+	}
+}
+
+// Close 关闭指定 id 对应的活跃连接；若不存在则返回错误。
+func (r *Registry) Close(id uint64) error {
+	r.mu.Lock()
+	entry, ok := r.conns[id]
+	r.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("连接 %d 不存在或已关闭", id)
+	}
+	return entry.conn.Close()
+}
+
+// Snapshot 返回当前活跃连接列表的一份快照，按 id 排序无要求。
+func (r *Registry) Snapshot() []*ConnEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]*ConnEntry, 0, len(r.conns))
+	for _, entry := range r.conns {
+		out = append(out, entry)
+	}
+	return out
+}
+
+// Totals 返回累计统计：已接受的连接总数，以及上下行累计字节数
+// （活跃连接的当前计数 + 已关闭连接留存的计数）。
+func (r *Registry) Totals() (totalAccepted, active uint64, bytesUp, bytesDown uint64) {
+	r.mu.Lock()
+	active = uint64(len(r.conns))
+	bytesUp = r.closedBytesUp.Load()
+	bytesDown = r.closedBytesDown.Load()
+	for _, entry := range r.conns {
+		bytesUp += entry.conn.BytesRead()
+		bytesDown += entry.conn.BytesWritten()
+	}
+	r.mu.Unlock()
+
+	return r.totalAccepted.Load(), active, bytesUp, bytesDown
+}
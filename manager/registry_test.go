@@ -0,0 +1,82 @@
+package manager
+
+import (
+	"net"
+	"testing"
+)
+
+func TestRegistryRegisterTracksBytesAndActiveCount(t *testing.T) {
+	r := NewRegistry()
+
+	clientConn, peer := net.Pipe()
+	defer peer.Close()
+
+	wrapped, entry := r.Register(clientConn, "example.com:80")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		peer.Write([]byte("hello"))
+		buf := make([]byte, 16)
+		peer.Read(buf)
+	}()
+
+	buf := make([]byte, 16)
+	n, err := wrapped.Read(buf)
+	if err != nil {
+		t.Fatalf("Read 失败: %v", err)
+	}
+	if _, err := wrapped.Write(buf[:n]); err != nil {
+		t.Fatalf("Write 失败: %v", err)
+	}
+	<-done
+
+	if entry.BytesUp() != uint64(n) {
+		t.Fatalf("BytesUp = %d, want %d", entry.BytesUp(), n)
+	}
+	if entry.BytesDown() != uint64(n) {
+		t.Fatalf("BytesDown = %d, want %d", entry.BytesDown(), n)
+	}
+
+	total, active, _, _ := r.Totals()
+	if total != 1 {
+		t.Fatalf("totalAccepted = %d, want 1", total)
+	}
+	if active != 1 {
+		t.Fatalf("active = %d, want 1", active)
+	}
+
+	r.Unregister(entry.ID)
+
+	_, active, bytesUp, bytesDown := r.Totals()
+	if active != 0 {
+		t.Fatalf("active 退出登记表后应为 0, got %d", active)
+	}
+	if bytesUp != uint64(n) || bytesDown != uint64(n) {
+		t.Fatalf("Unregister 后累计字节应保留: bytesUp=%d bytesDown=%d, want %d", bytesUp, bytesDown, n)
+	}
+}
+
+func TestRegistryCloseUnknownID(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Close(999); err == nil {
+		t.Fatal("期望关闭不存在的连接 id 返回错误")
+	}
+}
+
+func TestRegistryCloseClosesUnderlyingConn(t *testing.T) {
+	r := NewRegistry()
+
+	clientConn, peer := net.Pipe()
+	defer peer.Close()
+
+	wrapped, entry := r.Register(clientConn, "example.com:80")
+
+	if err := r.Close(entry.ID); err != nil {
+		t.Fatalf("Close 失败: %v", err)
+	}
+
+	if _, err := wrapped.Read(make([]byte, 1)); err == nil {
+		t.Fatal("期望 Close 之后 Read 返回错误")
+	}
+}
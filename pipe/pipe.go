@@ -0,0 +1,41 @@
+// Package pipe 提供连接双方之间的双向数据转发，
+// 供正向端口转发（main.go）和 SOCKS5 服务端（socks5 包）共用。
+package pipe
+
+import (
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Bidirectional 在 a 与 b 之间建立双向转发，直到任意一侧关闭或出错。
+// 每个方向的拷贝结束时都会关闭对端连接，两个方向都结束后才会返回，
+// 因此返回时 a 和 b 均已关闭。
+func Bidirectional(a, b net.Conn) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		defer b.Close() // 加速另一个goroutine的退出
+		if _, err := io.Copy(b, a); err != nil {
+			if !strings.Contains(err.Error(), "use of closed network connection") {
+				log.Printf("从 %s 到 %s 的数据流错误: %v", a.RemoteAddr(), b.RemoteAddr(), err)
+			}
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		defer a.Close() // 加速另一个goroutine的退出
+		if _, err := io.Copy(a, b); err != nil {
+			if !strings.Contains(err.Error(), "use of closed network connection") {
+				log.Printf("从 %s 到 %s 的数据流错误: %v", b.RemoteAddr(), a.RemoteAddr(), err)
+			}
+		}
+	}()
+
+	wg.Wait()
+}
@@ -0,0 +1,49 @@
+package tunnel
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// ICodec 在字节流和 IMessage 之间编解码。
+type ICodec interface {
+	Encode(msg IMessage) ([]byte, error)
+	Decode(r io.Reader) (IMessage, error)
+}
+
+// maxMessageLen 防止畸形长度字段导致一次性分配过大的缓冲区。
+const maxMessageLen = 16 << 20 // 16MiB
+
+// TLVCodec 是默认的编解码实现：uint32 大端消息 ID，接 uint32 大端长度，
+// 再接长度对应的payload，即 `MsgID | Length | Data`。
+type TLVCodec struct{}
+
+func (TLVCodec) Encode(msg IMessage) ([]byte, error) {
+	data := msg.Data()
+	buf := make([]byte, 8+len(data))
+	binary.BigEndian.PutUint32(buf[0:4], msg.MsgID())
+	binary.BigEndian.PutUint32(buf[4:8], uint32(len(data)))
+	copy(buf[8:], data)
+	return buf, nil
+}
+
+func (TLVCodec) Decode(r io.Reader) (IMessage, error) {
+	head := make([]byte, 8)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return nil, err
+	}
+
+	id := binary.BigEndian.Uint32(head[0:4])
+	length := binary.BigEndian.Uint32(head[4:8])
+	if length > maxMessageLen {
+		return nil, fmt.Errorf("tunnel: 消息长度 %d 超过上限 %d", length, maxMessageLen)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	return &Message{ID: id, Payload: data}, nil
+}
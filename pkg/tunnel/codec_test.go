@@ -0,0 +1,60 @@
+package tunnel
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTLVCodecRoundTrip(t *testing.T) {
+	cases := []struct {
+		name string
+		id   uint32
+		data []byte
+	}{
+		{"with payload", 42, []byte("hello world")},
+		{"empty payload", 7, nil},
+		{"zero id", 0, []byte{0x01}},
+	}
+
+	codec := TLVCodec{}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			encoded, err := codec.Encode(&Message{ID: tc.id, Payload: tc.data})
+			if err != nil {
+				t.Fatalf("Encode 失败: %v", err)
+			}
+
+			decoded, err := codec.Decode(bytes.NewReader(encoded))
+			if err != nil {
+				t.Fatalf("Decode 失败: %v", err)
+			}
+			if decoded.MsgID() != tc.id {
+				t.Errorf("MsgID 不一致: got %d, want %d", decoded.MsgID(), tc.id)
+			}
+			if !bytes.Equal(decoded.Data(), tc.data) {
+				t.Errorf("Data 不一致: got %v, want %v", decoded.Data(), tc.data)
+			}
+		})
+	}
+}
+
+func TestTLVCodecDecodeRejectsOversizedLength(t *testing.T) {
+	codec := TLVCodec{}
+	head := make([]byte, 8)
+	// MsgID 任意，Length 超过 maxMessageLen。
+	head[4], head[5], head[6], head[7] = 0xFF, 0xFF, 0xFF, 0xFF
+	if _, err := codec.Decode(bytes.NewReader(head)); err == nil {
+		t.Fatal("期望超过 maxMessageLen 的长度字段被拒绝，但 Decode 没有返回错误")
+	}
+}
+
+func TestTLVCodecDecodeRejectsTruncatedPacket(t *testing.T) {
+	codec := TLVCodec{}
+	encoded, err := codec.Encode(&Message{ID: 1, Payload: []byte("truncated")})
+	if err != nil {
+		t.Fatalf("Encode 失败: %v", err)
+	}
+	if _, err := codec.Decode(bytes.NewReader(encoded[:len(encoded)-2])); err == nil {
+		t.Fatal("期望被截断的数据包解码失败，但 Decode 没有返回错误")
+	}
+}
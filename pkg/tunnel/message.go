@@ -0,0 +1,22 @@
+// Package tunnel 把本工具的转发核心抽出为一个可独立使用的库，借鉴了
+// Zinx 那套「Router/Handler」框架思路：连接建立后默认仍是无脑的字节
+// 转发（passthrough），但调用方可以按消息 ID 注册 IRouter，在把数据
+// 转发到上游之前插入日志、限速、鉴权等中间处理。
+package tunnel
+
+// IMessage 是解码后的一条定长前缀消息。
+type IMessage interface {
+	MsgID() uint32
+	DataLen() uint32
+	Data() []byte
+}
+
+// Message 是 IMessage 的默认实现。
+type Message struct {
+	ID      uint32
+	Payload []byte
+}
+
+func (m *Message) MsgID() uint32   { return m.ID }
+func (m *Message) DataLen() uint32 { return uint32(len(m.Payload)) }
+func (m *Message) Data() []byte    { return m.Payload }
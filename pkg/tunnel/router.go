@@ -0,0 +1,70 @@
+package tunnel
+
+import "net"
+
+// IConnection 是暴露给 IRouter 的连接上下文：既能看到客户端连接，
+// 也能把处理后的数据发往已经建立好的上游连接。
+type IConnection interface {
+	Conn() net.Conn
+	RemoteAddr() net.Addr
+	SendToUpstream(data []byte) error
+}
+
+// IRouter 处理某个消息 ID 的一条消息。PreHandle/PostHandle 常用于日志、
+// 限速、鉴权一类与业务无关的中间处理，Handle 是真正的业务逻辑。
+type IRouter interface {
+	PreHandle(conn IConnection, msg IMessage)
+	Handle(conn IConnection, msg IMessage)
+	PostHandle(conn IConnection, msg IMessage)
+}
+
+// BaseRouter 提供三个空实现，自定义 Router 可以只嵌入它再重写需要的方法。
+type BaseRouter struct{}
+
+func (BaseRouter) PreHandle(IConnection, IMessage)  {}
+func (BaseRouter) Handle(IConnection, IMessage)     {}
+func (BaseRouter) PostHandle(IConnection, IMessage) {}
+
+// PassthroughRouter 是消息 ID 没有注册 handler 时使用的默认 Router：
+// 只把消息的 payload 原样写给上游，不包含 MsgID/Length 头。完全没有
+// 注册 handler 时 Server 不做解码，直接双向转发原始字节。
+type PassthroughRouter struct {
+	BaseRouter
+}
+
+func (PassthroughRouter) Handle(conn IConnection, msg IMessage) {
+	conn.SendToUpstream(msg.Data())
+}
+
+// RouterGroup 是按消息 ID 注册的 IRouter 集合。零值可直接使用，
+// 此时 Len() 为 0，Server 会退化为原始字节转发。
+type RouterGroup struct {
+	handlers map[uint32]IRouter
+}
+
+// Register 为指定的消息 ID 注册一个 IRouter，覆盖此前的注册。
+func (g *RouterGroup) Register(msgID uint32, router IRouter) {
+	if g.handlers == nil {
+		g.handlers = make(map[uint32]IRouter)
+	}
+	g.handlers[msgID] = router
+}
+
+// Get 返回 msgID 对应的 IRouter；未注册时返回 PassthroughRouter。
+func (g *RouterGroup) Get(msgID uint32) IRouter {
+	if g == nil || g.handlers == nil {
+		return PassthroughRouter{}
+	}
+	if r, ok := g.handlers[msgID]; ok {
+		return r
+	}
+	return PassthroughRouter{}
+}
+
+// Len 返回已注册的 handler 数量；为 0 时 Server 使用无解码的原始转发。
+func (g *RouterGroup) Len() int {
+	if g == nil {
+		return 0
+	}
+	return len(g.handlers)
+}
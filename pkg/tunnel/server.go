@@ -0,0 +1,139 @@
+package tunnel
+
+import (
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/proxy"
+
+	"github.com/lingyicute/Go-TCP-Proxy/pipe"
+)
+
+// IServer 是隧道服务端的最小接口。
+type IServer interface {
+	Start() error
+	Stop() error
+	Serve(ln net.Listener) error
+}
+
+// Config 描述一个 Server 实例。
+type Config struct {
+	ListenAddr   string
+	UpstreamAddr string
+	Dialer       proxy.Dialer // 用于拨通 UpstreamAddr，传 proxy.Direct 即为直连
+	Codec        ICodec       // 为 nil 时使用 TLVCodec{}
+	Router       *RouterGroup // 为 nil 或未注册任何 handler 时退化为原始字节转发
+}
+
+// Server 是 IServer 的默认实现。
+type Server struct {
+	cfg Config
+
+	mu       sync.Mutex
+	listener net.Listener
+}
+
+// NewServer 根据配置创建一个 Server。
+func NewServer(cfg Config) *Server {
+	if cfg.Codec == nil {
+		cfg.Codec = TLVCodec{}
+	}
+	if cfg.Dialer == nil {
+		cfg.Dialer = proxy.Direct
+	}
+	return &Server{cfg: cfg}
+}
+
+// Start 监听 cfg.ListenAddr 并开始接受连接。
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.cfg.ListenAddr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve 在给定的 listener 上接受连接，每个连接交给独立的 goroutine 处理。
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	s.listener = ln
+	s.mu.Unlock()
+
+	log.Printf("tunnel 服务已在 %s 启动", ln.Addr())
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// Stop 关闭监听端口，使 Serve 中的 Accept 循环退出。
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	ln := s.listener
+	s.mu.Unlock()
+	if ln == nil {
+		return nil
+	}
+	return ln.Close()
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	upstream, err := s.cfg.Dialer.Dial("tcp", s.cfg.UpstreamAddr)
+	if err != nil {
+		log.Printf("tunnel: 连接上游 %s 失败: %v", s.cfg.UpstreamAddr, err)
+		return
+	}
+	defer upstream.Close()
+
+	if s.cfg.Router.Len() == 0 {
+		// 没有注册任何 handler：和过去一样，原始字节直接双向转发。
+		pipe.Bidirectional(conn, upstream)
+		return
+	}
+
+	// 下行方向不需要经过本地协议终结，原样转发给客户端即可。
+	go func() {
+		if _, err := io.Copy(conn, upstream); err != nil {
+			if !strings.Contains(err.Error(), "use of closed network connection") {
+				log.Printf("tunnel: 下行转发出错: %v", err)
+			}
+		}
+	}()
+
+	ic := &connection{conn: conn, upstream: upstream}
+	for {
+		msg, err := s.cfg.Codec.Decode(conn)
+		if err != nil {
+			if err != io.EOF && !strings.Contains(err.Error(), "use of closed network connection") {
+				log.Printf("tunnel: 解码消息失败: %v", err)
+			}
+			return
+		}
+
+		router := s.cfg.Router.Get(msg.MsgID())
+		router.PreHandle(ic, msg)
+		router.Handle(ic, msg)
+		router.PostHandle(ic, msg)
+	}
+}
+
+// connection 是 IConnection 的默认实现。
+type connection struct {
+	conn     net.Conn
+	upstream net.Conn
+}
+
+func (c *connection) Conn() net.Conn       { return c.conn }
+func (c *connection) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
+func (c *connection) SendToUpstream(data []byte) error {
+	_, err := c.upstream.Write(data)
+	return err
+}
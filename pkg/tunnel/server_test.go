@@ -0,0 +1,29 @@
+package tunnel
+
+import (
+	"testing"
+	"time"
+)
+
+// TestServerStartStopConcurrent 在 Start 仍在另一个 goroutine 里给
+// s.listener 赋值的同时调用 Stop，用来在 -race 下验证两者对 listener
+// 字段的访问是同步的。
+func TestServerStartStopConcurrent(t *testing.T) {
+	s := NewServer(Config{ListenAddr: "127.0.0.1:0", UpstreamAddr: "127.0.0.1:0"})
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.Start() }()
+
+	// 让 Start() 有机会先跑到 net.Listen 之后、Accept 循环之前。
+	time.Sleep(10 * time.Millisecond)
+
+	if err := s.Stop(); err != nil {
+		t.Fatalf("Stop 失败: %v", err)
+	}
+
+	select {
+	case <-errCh:
+	case <-time.After(time.Second):
+		t.Fatal("Start 在 Stop 之后没有退出")
+	}
+}
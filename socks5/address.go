@@ -0,0 +1,95 @@
+package socks5
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+// readAddr 从 r 中读取一个 SOCKS5 地址（ATYP + ADDR + PORT），
+// 返回形如 "host:port" 的地址字符串。
+func readAddr(r io.Reader) (string, error) {
+	head := make([]byte, 1)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return "", fmt.Errorf("读取地址类型失败: %w", err)
+	}
+
+	var host string
+	switch head[0] {
+	case atypIPv4:
+		b := make([]byte, net.IPv4len)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return "", fmt.Errorf("读取 IPv4 地址失败: %w", err)
+		}
+		host = net.IP(b).String()
+	case atypIPv6:
+		b := make([]byte, net.IPv6len)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return "", fmt.Errorf("读取 IPv6 地址失败: %w", err)
+		}
+		host = net.IP(b).String()
+	case atypDomain:
+		lenBuf := make([]byte, 1)
+		if _, err := io.ReadFull(r, lenBuf); err != nil {
+			return "", fmt.Errorf("读取域名长度失败: %w", err)
+		}
+		b := make([]byte, lenBuf[0])
+		if _, err := io.ReadFull(r, b); err != nil {
+			return "", fmt.Errorf("读取域名失败: %w", err)
+		}
+		host = string(b)
+	default:
+		return "", fmt.Errorf("不支持的地址类型: 0x%02x", head[0])
+	}
+
+	portBuf := make([]byte, 2)
+	if _, err := io.ReadFull(r, portBuf); err != nil {
+		return "", fmt.Errorf("读取端口失败: %w", err)
+	}
+	port := binary.BigEndian.Uint16(portBuf)
+
+	return net.JoinHostPort(host, strconv.Itoa(int(port))), nil
+}
+
+// encodeAddr 将 net.Addr 编码为 SOCKS5 的 ATYP + ADDR + PORT 格式。
+// addr 为 nil 时返回一个全零的 IPv4 占位地址（0.0.0.0:0），
+// 这在应答失败场景或拿不到本地地址时是合法的。
+func encodeAddr(addr net.Addr) []byte {
+	var ip net.IP
+	var port int
+
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+		ip = tcpAddr.IP
+		port = tcpAddr.Port
+	} else if udpAddr, ok := addr.(*net.UDPAddr); ok {
+		ip = udpAddr.IP
+		port = udpAddr.Port
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		buf := make([]byte, 0, 1+net.IPv4len+2)
+		buf = append(buf, atypIPv4)
+		buf = append(buf, ip4...)
+		return appendPort(buf, port)
+	}
+	if ip16 := ip.To16(); ip16 != nil {
+		buf := make([]byte, 0, 1+net.IPv6len+2)
+		buf = append(buf, atypIPv6)
+		buf = append(buf, ip16...)
+		return appendPort(buf, port)
+	}
+
+	// 没有可用地址：退化为 0.0.0.0:0
+	buf := make([]byte, 0, 1+net.IPv4len+2)
+	buf = append(buf, atypIPv4)
+	buf = append(buf, net.IPv4zero.To4()...)
+	return appendPort(buf, 0)
+}
+
+func appendPort(buf []byte, port int) []byte {
+	portBuf := make([]byte, 2)
+	binary.BigEndian.PutUint16(portBuf, uint16(port))
+	return append(buf, portBuf...)
+}
@@ -0,0 +1,95 @@
+package socks5
+
+import (
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/lingyicute/Go-TCP-Proxy/pipe"
+)
+
+// handleBind 实现最小化的 BIND 命令：在本机打开一个临时监听端口，
+// 先回复绑定地址，再等待远端（由客户端告知的 addr 发起方）拨入，
+// 拨入成功后回复第二次应答并开始双向转发。
+func (s *Server) handleBind(conn net.Conn, addr string) {
+	log.Printf("SOCKS5 BIND: 客户端 %s，期望来自 %s 的入站连接", conn.RemoteAddr(), addr)
+
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		log.Printf("SOCKS5 BIND 无法创建临时监听: %v", err)
+		writeReply(conn, repGeneralFailure, nil)
+		return
+	}
+	defer ln.Close()
+
+	if err := writeReply(conn, repSucceeded, ln.Addr()); err != nil {
+		return
+	}
+
+	inbound, err := ln.Accept()
+	if err != nil {
+		log.Printf("SOCKS5 BIND 等待入站连接失败: %v", err)
+		writeReply(conn, repGeneralFailure, nil)
+		return
+	}
+	defer inbound.Close()
+
+	if err := checkBindOriginator(inbound.RemoteAddr(), addr); err != nil {
+		log.Printf("SOCKS5 BIND 拒绝非预期的入站连接: %v", err)
+		writeReply(conn, repConnNotAllowed, nil)
+		return
+	}
+
+	if err := writeReply(conn, repSucceeded, inbound.RemoteAddr()); err != nil {
+		return
+	}
+
+	if s.connHook != nil {
+		var done func()
+		conn, done = s.connHook(conn, inbound.RemoteAddr().String())
+		defer done()
+	}
+
+	pipe.Bidirectional(conn, inbound)
+	log.Printf("SOCKS5 BIND 连接 %s <-> %s 已关闭", conn.RemoteAddr(), inbound.RemoteAddr())
+}
+
+// checkBindOriginator 校验 inboundAddr（拨入临时监听端口的一方）是否
+// 就是客户端在 BIND 请求里声明的期望发起方 expected（"host:port"，
+// 只比较 IP，忽略端口，因为发起方通常不会从固定源端口连出）。这是
+// RFC 1928 对 BIND 的要求：没有这一步，任何抢先连上临时端口的第三方
+// 都会被直接接入客户端的 BIND 会话。
+func checkBindOriginator(inboundAddr net.Addr, expected string) error {
+	inboundHost, _, err := net.SplitHostPort(inboundAddr.String())
+	if err != nil {
+		return fmt.Errorf("无法解析入站地址 %q: %w", inboundAddr.String(), err)
+	}
+	inboundIP := net.ParseIP(inboundHost)
+	if inboundIP == nil {
+		return fmt.Errorf("入站地址 %q 不是合法 IP", inboundHost)
+	}
+
+	expectedHost, _, err := net.SplitHostPort(expected)
+	if err != nil {
+		return fmt.Errorf("无法解析期望的发起方地址 %q: %w", expected, err)
+	}
+
+	if expectedIP := net.ParseIP(expectedHost); expectedIP != nil {
+		if !expectedIP.Equal(inboundIP) {
+			return fmt.Errorf("期望来自 %s 的连接，实际来自 %s", expectedIP, inboundIP)
+		}
+		return nil
+	}
+
+	// 期望的发起方是域名：解析后检查入站 IP 是否在结果集合中。
+	resolved, err := net.LookupIP(expectedHost)
+	if err != nil {
+		return fmt.Errorf("无法解析期望的发起方域名 %q: %w", expectedHost, err)
+	}
+	for _, ip := range resolved {
+		if ip.Equal(inboundIP) {
+			return nil
+		}
+	}
+	return fmt.Errorf("期望来自域名 %s 的连接，实际来自 %s，且该 IP 不在解析结果中", expectedHost, inboundIP)
+}
@@ -0,0 +1,87 @@
+package socks5
+
+import (
+	"fmt"
+	"io"
+	"net"
+)
+
+// negotiate 完成 RFC 1928 的方法协商，以及按需进行的 RFC 1929
+// 用户名/密码子协商。
+func (s *Server) negotiate(conn net.Conn) error {
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return fmt.Errorf("读取问候消息失败: %w", err)
+	}
+	if head[0] != ver5 {
+		return fmt.Errorf("不支持的 SOCKS 版本: 0x%02x", head[0])
+	}
+
+	methods := make([]byte, head[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return fmt.Errorf("读取认证方法列表失败: %w", err)
+	}
+
+	wantAuth := s.cfg.Username != "" || s.cfg.Password != ""
+	chosen := byte(methodNoAcceptable)
+	for _, m := range methods {
+		if wantAuth && m == methodUserPass {
+			chosen = methodUserPass
+			break
+		}
+		if !wantAuth && m == methodNoAuth {
+			chosen = methodNoAuth
+			break
+		}
+	}
+
+	if _, err := conn.Write([]byte{ver5, chosen}); err != nil {
+		return fmt.Errorf("发送方法选择失败: %w", err)
+	}
+	if chosen == methodNoAcceptable {
+		return fmt.Errorf("客户端未提供可接受的认证方法")
+	}
+
+	if chosen == methodUserPass {
+		return s.authUserPass(conn)
+	}
+	return nil
+}
+
+// authUserPass 处理 RFC 1929 用户名/密码子协商。
+func (s *Server) authUserPass(conn net.Conn) error {
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return fmt.Errorf("读取认证子协商版本失败: %w", err)
+	}
+	if head[0] != 0x01 {
+		return fmt.Errorf("不支持的认证子协商版本: 0x%02x", head[0])
+	}
+
+	uname := make([]byte, head[1])
+	if _, err := io.ReadFull(conn, uname); err != nil {
+		return fmt.Errorf("读取用户名失败: %w", err)
+	}
+
+	plenBuf := make([]byte, 1)
+	if _, err := io.ReadFull(conn, plenBuf); err != nil {
+		return fmt.Errorf("读取密码长度失败: %w", err)
+	}
+	passwd := make([]byte, plenBuf[0])
+	if _, err := io.ReadFull(conn, passwd); err != nil {
+		return fmt.Errorf("读取密码失败: %w", err)
+	}
+
+	ok := string(uname) == s.cfg.Username && string(passwd) == s.cfg.Password
+	status := byte(0x00)
+	if !ok {
+		status = 0x01
+	}
+	if _, err := conn.Write([]byte{0x01, status}); err != nil {
+		return fmt.Errorf("发送认证结果失败: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("用户名或密码错误")
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package socks5
+
+import (
+	"io"
+	"net"
+	"testing"
+)
+
+func TestNegotiateNoAuth(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	s := NewServer(Config{})
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.negotiate(server) }()
+
+	if _, err := client.Write([]byte{ver5, 1, methodNoAuth}); err != nil {
+		t.Fatalf("写入问候消息失败: %v", err)
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(client, reply); err != nil {
+		t.Fatalf("读取方法选择失败: %v", err)
+	}
+	if reply[0] != ver5 || reply[1] != methodNoAuth {
+		t.Fatalf("期望选中 methodNoAuth，实际应答: %v", reply)
+	}
+
+	if err := <-errCh; err != nil {
+		t.Fatalf("negotiate 返回错误: %v", err)
+	}
+}
+
+func TestNegotiateUserPass(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	s := NewServer(Config{Username: "alice", Password: "secret"})
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.negotiate(server) }()
+
+	if _, err := client.Write([]byte{ver5, 1, methodUserPass}); err != nil {
+		t.Fatalf("写入问候消息失败: %v", err)
+	}
+
+	methodReply := make([]byte, 2)
+	if _, err := io.ReadFull(client, methodReply); err != nil {
+		t.Fatalf("读取方法选择失败: %v", err)
+	}
+	if methodReply[1] != methodUserPass {
+		t.Fatalf("期望选中 methodUserPass，实际应答: %v", methodReply)
+	}
+
+	req := []byte{0x01, byte(len("alice"))}
+	req = append(req, "alice"...)
+	req = append(req, byte(len("secret")))
+	req = append(req, "secret"...)
+	if _, err := client.Write(req); err != nil {
+		t.Fatalf("写入用户名密码失败: %v", err)
+	}
+
+	authReply := make([]byte, 2)
+	if _, err := io.ReadFull(client, authReply); err != nil {
+		t.Fatalf("读取认证结果失败: %v", err)
+	}
+	if authReply[1] != 0x00 {
+		t.Fatalf("期望认证成功，实际状态码: 0x%02x", authReply[1])
+	}
+
+	if err := <-errCh; err != nil {
+		t.Fatalf("negotiate 返回错误: %v", err)
+	}
+}
+
+func TestNegotiateUserPassWrongCredentials(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	s := NewServer(Config{Username: "alice", Password: "secret"})
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.negotiate(server) }()
+
+	if _, err := client.Write([]byte{ver5, 1, methodUserPass}); err != nil {
+		t.Fatalf("写入问候消息失败: %v", err)
+	}
+
+	methodReply := make([]byte, 2)
+	if _, err := io.ReadFull(client, methodReply); err != nil {
+		t.Fatalf("读取方法选择失败: %v", err)
+	}
+
+	req := []byte{0x01, byte(len("alice"))}
+	req = append(req, "alice"...)
+	req = append(req, byte(len("wrong")))
+	req = append(req, "wrong"...)
+	if _, err := client.Write(req); err != nil {
+		t.Fatalf("写入用户名密码失败: %v", err)
+	}
+
+	authReply := make([]byte, 2)
+	if _, err := io.ReadFull(client, authReply); err != nil {
+		t.Fatalf("读取认证结果失败: %v", err)
+	}
+	if authReply[1] == 0x00 {
+		t.Fatal("期望认证失败，实际状态码却是成功")
+	}
+
+	if err := <-errCh; err == nil {
+		t.Fatal("期望 negotiate 在认证失败时返回错误")
+	}
+}
@@ -0,0 +1,50 @@
+package socks5
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// readRequest 解析 RFC 1928 请求阶段的 VER/CMD/RSV/地址部分，
+// 返回命令字节以及目标地址（"host:port" 形式）。
+func readRequest(conn net.Conn) (cmd byte, addr string, err error) {
+	head := make([]byte, 3)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return 0, "", fmt.Errorf("读取请求头失败: %w", err)
+	}
+	if head[0] != ver5 {
+		return 0, "", fmt.Errorf("不支持的 SOCKS 版本: 0x%02x", head[0])
+	}
+
+	addr, err = readAddr(conn)
+	if err != nil {
+		return 0, "", err
+	}
+	return head[1], addr, nil
+}
+
+// writeReply 按 RFC 1928 格式发送请求阶段的应答。bindAddr 为 nil 时
+// 使用 0.0.0.0:0 占位，适用于失败应答。
+func writeReply(conn net.Conn, rep byte, bindAddr net.Addr) error {
+	buf := []byte{ver5, rep, 0x00}
+	buf = append(buf, encodeAddr(bindAddr)...)
+	_, err := conn.Write(buf)
+	return err
+}
+
+// replyCodeForDialErr 根据拨号失败的原因挑选一个合理的 SOCKS5 应答码。
+func replyCodeForDialErr(err error) byte {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "refused"):
+		return repConnRefused
+	case strings.Contains(msg, "network is unreachable"):
+		return repNetworkUnreachable
+	case strings.Contains(msg, "no route to host"), strings.Contains(msg, "no such host"):
+		return repHostUnreachable
+	default:
+		return repGeneralFailure
+	}
+}
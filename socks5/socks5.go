@@ -0,0 +1,178 @@
+// Package socks5 实现了一个最小可用的 RFC 1928/1929 SOCKS5 服务端，
+// 使本工具除了作为端口转发客户端外，也能反过来充当 SOCKS5 代理服务器。
+package socks5
+
+import (
+	"log"
+	"net"
+	"sync"
+
+	"github.com/lingyicute/Go-TCP-Proxy/pipe"
+)
+
+const (
+	ver5 = 0x05
+
+	// 认证方法
+	methodNoAuth       = 0x00
+	methodUserPass     = 0x02
+	methodNoAcceptable = 0xff
+
+	// 请求命令
+	cmdConnect      = 0x01
+	cmdBind         = 0x02
+	cmdUDPAssociate = 0x03
+
+	// 地址类型
+	atypIPv4   = 0x01
+	atypDomain = 0x03
+	atypIPv6   = 0x04
+
+	// 应答状态码
+	repSucceeded            = 0x00
+	repGeneralFailure       = 0x01
+	repConnNotAllowed       = 0x02
+	repNetworkUnreachable   = 0x03
+	repHostUnreachable      = 0x04
+	repConnRefused          = 0x05
+	repTTLExpired           = 0x06
+	repCmdNotSupported      = 0x07
+	repAddrTypeNotSupported = 0x08
+)
+
+// Config 描述 SOCKS5 服务端的配置，对应 config.json 中的 "socks5" 字段。
+type Config struct {
+	ListenAddr string `json:"listen_addr"`
+	// Username/Password 非空时启用 RFC 1929 用户名/密码子协商；
+	// 二者均为空时使用 "无需认证" 方式。
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// ConnHook 在一条连接的目标地址确定、即将开始双向转发之前被调用，
+// 可以用来包装 conn（例如接入 manager 包的 countingConn 以统计流量）。
+// 返回的 net.Conn 在此之后代替原始 conn 使用；done 会在转发结束后
+// 被调用一次，用于做登记收尾（例如从 Registry 中移除该连接）。
+type ConnHook func(conn net.Conn, remoteAddr string) (wrapped net.Conn, done func())
+
+// Server 是一个 SOCKS5 代理服务端。
+type Server struct {
+	cfg Config
+
+	// connHook 和 acceptGate 为可选的运行时管理钩子，默认都是 nil，
+	// 此时行为与没有 manager 包接入时完全一致。
+	connHook   ConnHook
+	acceptGate func() bool
+
+	mu       sync.Mutex
+	listener net.Listener
+}
+
+// NewServer 根据配置创建一个 SOCKS5 服务端。
+func NewServer(cfg Config) *Server {
+	return &Server{cfg: cfg}
+}
+
+// SetConnHook 注册一个 ConnHook，供外部（如 manager 包）登记连接并统计
+// 流量。传 nil 可以取消注册。
+func (s *Server) SetConnHook(hook ConnHook) {
+	s.connHook = hook
+}
+
+// SetAcceptGate 注册一个回调，Serve 的 accept 循环在分发每条新连接前
+// 都会调用它；返回 false 时该连接会被立即关闭。传 nil 表示始终接受。
+func (s *Server) SetAcceptGate(gate func() bool) {
+	s.acceptGate = gate
+}
+
+// ListenAndServe 监听 cfg.ListenAddr 并开始接受连接，直到发生错误。
+func (s *Server) ListenAndServe() error {
+	ln, err := net.Listen("tcp", s.cfg.ListenAddr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve 在给定的 listener 上接受连接，每个连接交给独立的 goroutine 处理。
+// Stop 被调用或 listener 关闭后返回。
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	s.listener = ln
+	s.mu.Unlock()
+
+	log.Printf("SOCKS5 服务已在 %s 启动", ln.Addr())
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		if s.acceptGate != nil && !s.acceptGate() {
+			conn.Close()
+			continue
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// Stop 关闭监听端口，使 Serve 中的 Accept 循环退出。
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Close()
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	if err := s.negotiate(conn); err != nil {
+		log.Printf("SOCKS5 协商失败 (客户端 %s): %v", conn.RemoteAddr(), err)
+		return
+	}
+
+	cmd, addr, err := readRequest(conn)
+	if err != nil {
+		log.Printf("SOCKS5 请求解析失败 (客户端 %s): %v", conn.RemoteAddr(), err)
+		writeReply(conn, repGeneralFailure, nil)
+		return
+	}
+
+	switch cmd {
+	case cmdConnect:
+		s.handleConnect(conn, addr)
+	case cmdBind:
+		s.handleBind(conn, addr)
+	case cmdUDPAssociate:
+		s.handleUDPAssociate(conn, addr)
+	default:
+		writeReply(conn, repCmdNotSupported, nil)
+	}
+}
+
+func (s *Server) handleConnect(conn net.Conn, addr string) {
+	log.Printf("SOCKS5 CONNECT: 客户端 %s -> %s", conn.RemoteAddr(), addr)
+
+	target, err := net.Dial("tcp", addr)
+	if err != nil {
+		log.Printf("SOCKS5 CONNECT 拨号 %s 失败: %v", addr, err)
+		writeReply(conn, replyCodeForDialErr(err), nil)
+		return
+	}
+	defer target.Close()
+
+	if err := writeReply(conn, repSucceeded, target.LocalAddr()); err != nil {
+		return
+	}
+
+	if s.connHook != nil {
+		var done func()
+		conn, done = s.connHook(conn, addr)
+		defer done()
+	}
+
+	pipe.Bidirectional(conn, target)
+	log.Printf("SOCKS5 CONNECT 连接 %s -> %s 已关闭", conn.RemoteAddr(), addr)
+}
@@ -0,0 +1,179 @@
+package socks5
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// udpAssociation 维护一个 UDP ASSOCIATE 会话：本端用于收发封装数据包的
+// udpConn，以及按目标地址拆分的转发连接。
+type udpAssociation struct {
+	udpConn    *net.UDPConn
+	clientAddr *net.UDPAddr
+
+	mu     sync.Mutex
+	relays map[string]*net.UDPConn
+}
+
+// handleUDPAssociate 实现 UDP ASSOCIATE：开一个临时 UDP 端口用于和客户端
+// 交换按 RFC 1928 封装的数据报，并在控制连接(TCP)断开时销毁整个关联。
+func (s *Server) handleUDPAssociate(conn net.Conn, addr string) {
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4zero, Port: 0})
+	if err != nil {
+		log.Printf("SOCKS5 UDP ASSOCIATE 创建 UDP 端口失败: %v", err)
+		writeReply(conn, repGeneralFailure, nil)
+		return
+	}
+	defer udpConn.Close()
+
+	if err := writeReply(conn, repSucceeded, udpConn.LocalAddr()); err != nil {
+		return
+	}
+	log.Printf("SOCKS5 UDP ASSOCIATE: 客户端 %s 已在 %s 建立关联", conn.RemoteAddr(), udpConn.LocalAddr())
+
+	// 控制连接一旦关闭（或出现任何数据/错误），关联即应随之销毁。
+	done := make(chan struct{})
+	go func() {
+		buf := make([]byte, 1)
+		conn.Read(buf)
+		close(done)
+		udpConn.Close()
+	}()
+
+	assoc := &udpAssociation{udpConn: udpConn, relays: make(map[string]*net.UDPConn)}
+	defer assoc.closeAll()
+
+	buf := make([]byte, 65536)
+	for {
+		n, clientAddr, err := udpConn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-done:
+			default:
+				log.Printf("SOCKS5 UDP ASSOCIATE 读取失败: %v", err)
+			}
+			return
+		}
+
+		if assoc.clientAddr == nil {
+			assoc.clientAddr = clientAddr
+		} else if !assoc.clientAddr.IP.Equal(clientAddr.IP) {
+			continue // 忽略非本次关联客户端的数据包
+		}
+
+		targetAddr, payload, err := decodeUDPHeader(buf[:n])
+		if err != nil {
+			log.Printf("SOCKS5 UDP 数据包头解析失败: %v", err)
+			continue
+		}
+		assoc.forward(targetAddr, payload, clientAddr)
+	}
+}
+
+func (a *udpAssociation) forward(targetAddr string, payload []byte, clientAddr *net.UDPAddr) {
+	a.mu.Lock()
+	relay, ok := a.relays[targetAddr]
+	if !ok {
+		udpAddr, err := net.ResolveUDPAddr("udp", targetAddr)
+		if err != nil {
+			a.mu.Unlock()
+			log.Printf("SOCKS5 UDP 目标地址解析失败 %s: %v", targetAddr, err)
+			return
+		}
+		relay, err = net.DialUDP("udp", nil, udpAddr)
+		if err != nil {
+			a.mu.Unlock()
+			log.Printf("SOCKS5 UDP 转发拨号失败 %s: %v", targetAddr, err)
+			return
+		}
+		a.relays[targetAddr] = relay
+		go a.relayReplies(targetAddr, relay, clientAddr)
+	}
+	a.mu.Unlock()
+
+	if _, err := relay.Write(payload); err != nil {
+		log.Printf("SOCKS5 UDP 转发到 %s 失败: %v", targetAddr, err)
+	}
+}
+
+// relayReplies 把目标服务器回复的数据报重新封装为 SOCKS5 UDP 格式，
+// 发回给客户端，直到 relay 连接关闭。
+func (a *udpAssociation) relayReplies(targetAddr string, relay *net.UDPConn, clientAddr *net.UDPAddr) {
+	buf := make([]byte, 65536)
+	for {
+		n, err := relay.Read(buf)
+		if err != nil {
+			return
+		}
+		packet, err := encodeUDPHeader(targetAddr, buf[:n])
+		if err != nil {
+			log.Printf("SOCKS5 UDP 回包封装失败: %v", err)
+			continue
+		}
+		if _, err := a.udpConn.WriteToUDP(packet, clientAddr); err != nil {
+			return
+		}
+	}
+}
+
+func (a *udpAssociation) closeAll() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, r := range a.relays {
+		r.Close()
+	}
+}
+
+// decodeUDPHeader 解析 RFC 1928 的 UDP 请求头：
+// RSV(2) | FRAG(1) | ATYP(1) | DST.ADDR | DST.PORT | DATA
+// 不支持分片（FRAG != 0 的数据包会被拒绝）。
+func decodeUDPHeader(pkt []byte) (targetAddr string, payload []byte, err error) {
+	if len(pkt) < 4 {
+		return "", nil, fmt.Errorf("数据包过短")
+	}
+	if pkt[2] != 0x00 {
+		return "", nil, fmt.Errorf("不支持分片的 UDP 数据包 (FRAG=0x%02x)", pkt[2])
+	}
+
+	r := bytes.NewReader(pkt[3:])
+	targetAddr, err = readAddr(r)
+	if err != nil {
+		return "", nil, err
+	}
+	remaining := r.Len()
+	payload = pkt[len(pkt)-remaining:]
+	return targetAddr, payload, nil
+}
+
+// encodeUDPHeader 按 RFC 1928 格式给 payload 加上 UDP 请求头。
+func encodeUDPHeader(fromAddr string, payload []byte) ([]byte, error) {
+	host, portStr, err := net.SplitHostPort(fromAddr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("非法端口 %q: %w", portStr, err)
+	}
+
+	header := []byte{0x00, 0x00, 0x00}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			header = append(header, atypIPv4)
+			header = append(header, ip4...)
+		} else {
+			header = append(header, atypIPv6)
+			header = append(header, ip.To16()...)
+		}
+	} else {
+		header = append(header, atypDomain, byte(len(host)))
+		header = append(header, host...)
+	}
+	header = appendPort(header, port)
+
+	return append(header, payload...), nil
+}
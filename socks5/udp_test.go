@@ -0,0 +1,52 @@
+package socks5
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestUDPHeaderRoundTrip(t *testing.T) {
+	cases := []struct {
+		name    string
+		addr    string
+		payload []byte
+	}{
+		{"ipv4", "192.168.1.1:53", []byte("hello")},
+		{"ipv6", "[2001:db8::1]:8080", []byte{0x01, 0x02, 0x03}},
+		{"domain", "example.com:443", []byte("domain payload")},
+		{"empty payload", "10.0.0.1:1234", nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			pkt, err := encodeUDPHeader(tc.addr, tc.payload)
+			if err != nil {
+				t.Fatalf("encodeUDPHeader 失败: %v", err)
+			}
+
+			gotAddr, gotPayload, err := decodeUDPHeader(pkt)
+			if err != nil {
+				t.Fatalf("decodeUDPHeader 失败: %v", err)
+			}
+			if gotAddr != tc.addr {
+				t.Errorf("地址不一致: got %q, want %q", gotAddr, tc.addr)
+			}
+			if !bytes.Equal(gotPayload, tc.payload) {
+				t.Errorf("payload 不一致: got %v, want %v", gotPayload, tc.payload)
+			}
+		})
+	}
+}
+
+func TestDecodeUDPHeaderRejectsFragments(t *testing.T) {
+	pkt := []byte{0x00, 0x00, 0x01, atypIPv4, 127, 0, 0, 1, 0, 80}
+	if _, _, err := decodeUDPHeader(pkt); err == nil {
+		t.Fatal("期望 FRAG != 0 的数据包被拒绝，但 decodeUDPHeader 没有返回错误")
+	}
+}
+
+func TestDecodeUDPHeaderRejectsShortPacket(t *testing.T) {
+	if _, _, err := decodeUDPHeader([]byte{0x00, 0x00}); err == nil {
+		t.Fatal("期望过短的数据包被拒绝，但 decodeUDPHeader 没有返回错误")
+	}
+}
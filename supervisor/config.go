@@ -0,0 +1,14 @@
+package supervisor
+
+import "github.com/lingyicute/Go-TCP-Proxy/dialer"
+
+// Config 是 forward 模式里会受热加载影响的那部分配置：本地监听地址、
+// 固定转发目标，以及拨号路由。main.Config 匿名内嵌它，JSON 字段
+// 因此仍然铺平在顶层，和历史配置保持兼容。
+type Config struct {
+	LocalAddr  string                        `json:"local_addr"`
+	RemoteAddr string                        `json:"remote_addr"`
+	SocksAddr  string                        `json:"socks_addr"`
+	Chains     map[string]dialer.ChainConfig `json:"chains,omitempty"`
+	Routes     []dialer.RouteConfig          `json:"routes,omitempty"`
+}
@@ -0,0 +1,178 @@
+// Package supervisor 把「监听端口 + 拨号路由」的生命周期收拢成一个
+// 状态机，使得 config.json 的热加载（SIGHUP 或管理端点触发）可以在
+// 不中断已有连接的前提下完成：LocalAddr 变化时开新监听、让旧监听上
+// 的连接自然跑完；RemoteAddr/SocksAddr/chains/routes 变化时只是原子
+// 替换后续新连接会用到的拨号器。
+package supervisor
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/net/proxy"
+
+	"github.com/lingyicute/Go-TCP-Proxy/dialer"
+)
+
+// ConnHandler 处理一个已经接受的连接；d 是为这条连接挑选好的拨号器。
+// Supervisor 负责在调用它前后维护在途连接计数，handler 无需关心。
+type ConnHandler func(clientConn net.Conn, remoteAddr string, d proxy.Dialer)
+
+// Supervisor 持有当前生效的监听器、配置快照和拨号路由，是 forward
+// 模式下配置热加载的唯一入口。
+type Supervisor struct {
+	handler    ConnHandler
+	acceptGate func() bool // 为 nil 或返回 true 时才接受新连接
+
+	cfg      atomic.Pointer[Config]
+	router   atomic.Pointer[dialer.Router]
+	fallback atomic.Pointer[proxy.Dialer]
+
+	mu       sync.Mutex
+	listener net.Listener
+
+	wg sync.WaitGroup // 在途连接登记：所有历史监听器接受的连接共用同一个 wg
+}
+
+// New 根据初始配置创建 Supervisor，但还不会开始监听。acceptGate 可以
+// 为 nil，表示始终接受新连接。
+func New(cfg *Config, handler ConnHandler, acceptGate func() bool) (*Supervisor, error) {
+	s := &Supervisor{handler: handler, acceptGate: acceptGate}
+	s.cfg.Store(cfg)
+	if err := s.rebuildDialer(cfg); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// rebuildDialer 根据配置构建拨号路由：未声明 chains/routes 时退化为
+// 原有的单一固定 SOCKS5 拨号器。
+func (s *Supervisor) rebuildDialer(cfg *Config) error {
+	if len(cfg.Chains) == 0 && len(cfg.Routes) == 0 {
+		d, err := proxy.SOCKS5("tcp", cfg.SocksAddr, nil, proxy.Direct)
+		if err != nil {
+			return fmt.Errorf("创建 SOCKS5 代理拨号器失败: %w", err)
+		}
+		s.router.Store(nil)
+		s.fallback.Store(&d)
+		return nil
+	}
+
+	r, err := dialer.NewRouter(cfg.Chains, cfg.Routes)
+	if err != nil {
+		return err
+	}
+	var direct proxy.Dialer = proxy.Direct
+	s.router.Store(r)
+	s.fallback.Store(&direct)
+	return nil
+}
+
+func (s *Supervisor) dialerFor(remoteAddr, srcAddr string) proxy.Dialer {
+	if r := s.router.Load(); r != nil {
+		return r.DialerFor(remoteAddr, srcAddr)
+	}
+	if fb := s.fallback.Load(); fb != nil {
+		return *fb
+	}
+	return proxy.Direct
+}
+
+// Start 打开 cfg.LocalAddr 上的监听并开始接受连接。
+func (s *Supervisor) Start() error {
+	cfg := s.cfg.Load()
+	ln, err := net.Listen("tcp", cfg.LocalAddr)
+	if err != nil {
+		return fmt.Errorf("无法监听本地端口 %s: %w", cfg.LocalAddr, err)
+	}
+
+	s.mu.Lock()
+	s.listener = ln
+	s.mu.Unlock()
+
+	s.acceptLoop(ln)
+	return nil
+}
+
+func (s *Supervisor) acceptLoop(ln net.Listener) {
+	go func() {
+		for {
+			clientConn, err := ln.Accept()
+			if err != nil {
+				return // listener 被 Reload/Stop 关闭，正常退出
+			}
+			if s.acceptGate != nil && !s.acceptGate() {
+				clientConn.Close()
+				continue
+			}
+
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				cfg := s.cfg.Load()
+				d := s.dialerFor(cfg.RemoteAddr, clientConn.RemoteAddr().String())
+				s.handler(clientConn, cfg.RemoteAddr, d)
+			}()
+		}
+	}()
+}
+
+// Reload 应用新配置：按需重建拨号路由、按需切换监听器，均不影响已经
+// 建立的连接。只有在新监听器（如需要）已经打开成功、拨号路由也重建
+// 成功之后，才会把 s.cfg 切换到 newCfg —— 否则任何一步失败时，
+// s.cfg 和实际生效的监听器/路由都必须保持和失败前一致，不能出现
+// “cfg 已经指向新地址，但真正监听的还是旧地址”这种不一致状态。
+func (s *Supervisor) Reload(newCfg *Config) error {
+	old := s.cfg.Load()
+
+	var newLn net.Listener
+	if newCfg.LocalAddr != old.LocalAddr {
+		var err error
+		newLn, err = net.Listen("tcp", newCfg.LocalAddr)
+		if err != nil {
+			return fmt.Errorf("无法监听新的本地端口 %s: %w", newCfg.LocalAddr, err)
+		}
+	}
+
+	if err := s.rebuildDialer(newCfg); err != nil {
+		if newLn != nil {
+			newLn.Close()
+		}
+		return err
+	}
+
+	s.cfg.Store(newCfg)
+
+	if newLn == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	oldLn := s.listener
+	s.listener = newLn
+	s.mu.Unlock()
+
+	s.acceptLoop(newLn)
+	if oldLn != nil {
+		oldLn.Close() // 停止在旧端口上 Accept，已有连接通过共享的 wg 自然跑完
+	}
+	return nil
+}
+
+// Stop 关闭当前监听器，使 accept 循环退出；不会等待在途连接结束。
+func (s *Supervisor) Stop() error {
+	s.mu.Lock()
+	ln := s.listener
+	s.mu.Unlock()
+	if ln == nil {
+		return nil
+	}
+	return ln.Close()
+}
+
+// Wait 阻塞直到所有已接受的连接（跨所有监听器世代）都处理完毕。
+func (s *Supervisor) Wait() {
+	s.wg.Wait()
+}
@@ -0,0 +1,108 @@
+package supervisor
+
+import (
+	"net"
+	"testing"
+
+	"golang.org/x/net/proxy"
+)
+
+func noopHandler(clientConn net.Conn, remoteAddr string, d proxy.Dialer) {
+	clientConn.Close()
+}
+
+// freeAddr 临时监听一个端口拿到地址，随后立刻关闭，把端口让回去。
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("无法获取空闲端口: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+// TestReloadRollsBackCfgOnFailedListen 直接对应曾经出现过的一个问题：
+// Reload 在新端口监听失败时不能提前把 cfg 切换过去，否则 cfg 会指向一
+// 个实际上没有监听器的地址，并导致之后合法的回退 Reload 因为
+// "cfg 以为地址变了、其实没变" 而去重新监听一个仍被占用的旧地址。
+func TestReloadRollsBackCfgOnFailedListen(t *testing.T) {
+	addr := freeAddr(t)
+
+	sup, err := New(&Config{LocalAddr: addr, SocksAddr: "127.0.0.1:1"}, noopHandler, nil)
+	if err != nil {
+		t.Fatalf("New 失败: %v", err)
+	}
+	if err := sup.Start(); err != nil {
+		t.Fatalf("Start 失败: %v", err)
+	}
+	defer sup.Stop()
+
+	busyLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("无法监听占位端口: %v", err)
+	}
+	defer busyLn.Close()
+	busyAddr := busyLn.Addr().String()
+
+	if err := sup.Reload(&Config{LocalAddr: busyAddr, SocksAddr: "127.0.0.1:1"}); err == nil {
+		t.Fatal("期望 Reload 到已被占用的地址返回错误")
+	}
+
+	if got := sup.cfg.Load().LocalAddr; got != addr {
+		t.Fatalf("Reload 失败后 cfg.LocalAddr = %q, 期望保持原值 %q", got, addr)
+	}
+
+	if conn, err := net.Dial("tcp", addr); err != nil {
+		t.Fatalf("原监听器应当仍然存活，却拨号失败: %v", err)
+	} else {
+		conn.Close()
+	}
+
+	// 回退 Reload 到原地址：如果上一步失败时 cfg 被错误地提前切换，这里
+	// 会被当成"地址变化"而尝试重新监听一个仍被原监听器占用的地址，从而
+	// 以 "address already in use" 失败。
+	if err := sup.Reload(&Config{LocalAddr: addr, SocksAddr: "127.0.0.1:1"}); err != nil {
+		t.Fatalf("回退到原地址的 Reload 应当成功, got: %v", err)
+	}
+}
+
+func TestReloadSwitchesListenerOnAddrChange(t *testing.T) {
+	addr1 := freeAddr(t)
+
+	sup, err := New(&Config{LocalAddr: addr1, SocksAddr: "127.0.0.1:1"}, noopHandler, nil)
+	if err != nil {
+		t.Fatalf("New 失败: %v", err)
+	}
+	if err := sup.Start(); err != nil {
+		t.Fatalf("Start 失败: %v", err)
+	}
+	defer sup.Stop()
+
+	addr2 := freeAddr(t)
+	if err := sup.Reload(&Config{LocalAddr: addr2, SocksAddr: "127.0.0.1:1"}); err != nil {
+		t.Fatalf("Reload 到新地址失败: %v", err)
+	}
+
+	if conn, err := net.Dial("tcp", addr2); err != nil {
+		t.Fatalf("新监听地址应当可以连接: %v", err)
+	} else {
+		conn.Close()
+	}
+
+	if got := sup.cfg.Load().LocalAddr; got != addr2 {
+		t.Fatalf("cfg.LocalAddr = %q, 期望 %q", got, addr2)
+	}
+}
+
+func TestDialerForReturnsDialerWithoutRoutes(t *testing.T) {
+	sup, err := New(&Config{LocalAddr: "127.0.0.1:0", SocksAddr: "127.0.0.1:1"}, noopHandler, nil)
+	if err != nil {
+		t.Fatalf("New 失败: %v", err)
+	}
+
+	if d := sup.dialerFor("example.com:443", "1.2.3.4:1111"); d == nil {
+		t.Fatal("未配置 chains/routes 时也应当返回一个可用的拨号器")
+	}
+}